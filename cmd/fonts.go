@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/xi-mad/MontageGo/internal/fonts"
+
+	"github.com/spf13/cobra"
+)
+
+var fontsCmd = &cobra.Command{
+	Use:   "fonts",
+	Short: "Inspect the fonts available for text rendering.",
+}
+
+var fontsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed fonts usable with --font and --font-fallback.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installed := fonts.Installed()
+		if len(installed) == 0 {
+			return fmt.Errorf("no fonts found in the system font directories")
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FAMILY\tSTYLE\tFULL NAME\tFILE")
+		for _, info := range installed {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Family, info.Style, info.FullName, info.Spec())
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	fontsCmd.AddCommand(fontsListCmd)
+	rootCmd.AddCommand(fontsCmd)
+}
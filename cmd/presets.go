@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/xi-mad/MontageGo/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+var presetsCmd = &cobra.Command{
+	Use:   "presets",
+	Short: "Inspect the style presets available to --preset.",
+}
+
+var presetsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the built-in presets and those in the user preset directory.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tDESCRIPTION\tSOURCE")
+		for _, info := range config.Presets() {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", info.Name, info.Description, info.Source)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if dir, err := config.PresetDir(); err == nil {
+			fmt.Printf("\nUser presets are read from %s\n", dir)
+		}
+		return nil
+	},
+}
+
+func init() {
+	presetsCmd.AddCommand(presetsListCmd)
+	rootCmd.AddCommand(presetsCmd)
+}
@@ -22,19 +22,18 @@ var rootCmd = &cobra.Command{
 	Long:  `MontageGo is a smart wrapper for FFmpeg to generate beautiful and informative thumbnail sheets for video files.`,
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Load config file first (so CLI overrides it)
-		if configPath != "" {
-			fileCfg, err := config.Load(configPath)
-			if err != nil {
-				return fmt.Errorf("failed to load config file: %w", err)
-			}
-			mergeConfig(cmd, cfg, fileCfg)
+		if err := loadConfig(cmd, cfg); err != nil {
+			return err
 		}
 
 		if cfg.Quiet && cfg.Verbose {
 			return fmt.Errorf("flags --quiet and --verbose cannot be used together")
 		}
 
+		if cfg.OutputPath == "-" && cfg.SidecarPath == "-" {
+			return fmt.Errorf("flags --output and --sidecar cannot both write to stdout")
+		}
+
 		// --quiet is a shorthand for hiding both log types
 		if cfg.Quiet {
 			cfg.ShowAppLog = false
@@ -55,21 +54,26 @@ func SetVersion(version string) {
 func runMontage(cfg *config.Config) error {
 	// Determine the output stream for application logs.
 	logWriter := os.Stdout
-	if cfg.OutputPath == "-" {
+	if cfg.OutputPath == "-" || cfg.SidecarPath == "-" {
 		logWriter = os.Stderr
 	}
 
 	if cfg.OutputPath == "" {
 		inputDir := filepath.Dir(cfg.InputPath)
 		baseName := strings.TrimSuffix(filepath.Base(cfg.InputPath), filepath.Ext(cfg.InputPath))
-		newFileName := baseName + "_montage.jpg"
+		ext := ".jpg"
+		switch strings.ToLower(cfg.OutputFormat) {
+		case "png", "webp":
+			ext = "." + strings.ToLower(cfg.OutputFormat)
+		}
+		newFileName := baseName + "_montage" + ext
 		cfg.OutputPath = filepath.Join(inputDir, newFileName)
 	}
 
 	if cfg.ShowAppLog {
 		fmt.Fprintln(logWriter, "Analyzing video file:", cfg.InputPath)
 	}
-	videoInfo, err := ffprobe.GetVideoInfo(cfg.InputPath, cfg.FfprobePath)
+	videoInfo, err := ffprobe.GetVideoInfo(cfg.InputPath, cfg.FfprobePath, cfg.VideoStream)
 	if err != nil {
 		return fmt.Errorf("failed to get video info: %w", err)
 	}
@@ -106,9 +110,11 @@ func init() {
 
 	// Config file
 	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Path to YAML config file")
+	rootCmd.PersistentFlags().StringVar(&cfg.Preset, "preset", "", "Style preset applied beneath the config file and flags: a built-in (dark, light, print, minimal) or user preset name (see 'presets list'), or a YAML file")
 
 	// File and Path Flags
 	rootCmd.PersistentFlags().StringVarP(&cfg.OutputPath, "output", "o", "", "Output path. Use '-' to stream image data to stdout.")
+	rootCmd.PersistentFlags().StringVar(&cfg.OutputFormat, "format", "auto", "Output image format: auto (from the --output extension, JPEG otherwise), jpeg, png or webp (encoded by ffmpeg with libwebp)")
 
 	rootCmd.PersistentFlags().IntVarP(&cfg.Columns, "columns", "c", 4, "Number of columns in the grid")
 	rootCmd.PersistentFlags().IntVarP(&cfg.Rows, "rows", "r", 5, "Number of rows in the grid")
@@ -116,15 +122,88 @@ func init() {
 	rootCmd.PersistentFlags().IntVar(&cfg.ThumbHeight, "thumb-height", -1, "Height of each thumbnail. Defaults to -1 (auto-scale based on width and aspect ratio)")
 	rootCmd.PersistentFlags().IntVar(&cfg.Padding, "padding", 5, "Padding between thumbnails")
 	rootCmd.PersistentFlags().IntVar(&cfg.Margin, "margin", 20, "Margin around the grid")
-	rootCmd.PersistentFlags().IntVar(&cfg.HeaderHeight, "header", 120, "Height of the header section")
+	rootCmd.PersistentFlags().IntVar(&cfg.CanvasWidth, "canvas-width", 0, "Target width of the sheet; the thumbnail width is computed to fit it. 0 uses --thumb-width")
+	rootCmd.PersistentFlags().DurationVar(&cfg.Interval, "interval", 0, "One tile per interval of the video (e.g. 2m, 90s); columns and rows are chosen automatically")
+	rootCmd.PersistentFlags().IntVar(&cfg.TileCount, "count", 0, "Total number of tiles; columns and rows are chosen automatically and the last row may be short")
+	rootCmd.PersistentFlags().IntVar(&cfg.HeaderHeight, "header", -1, "Height of the header section. Defaults to -1 (fit the header text); 0 hides the header, other values cut off lines that do not fit")
+	rootCmd.PersistentFlags().StringVar(&cfg.HeaderStyle, "header-style", "centered", "Header layout: centered, left (left-aligned with a poster frame) or table (key/value table)")
+	rootCmd.PersistentFlags().StringVar(&cfg.HeaderPosition, "header-position", "top", "Where the header goes: top or bottom (as a footer)")
+	rootCmd.PersistentFlags().StringVar(&cfg.HeaderTemplate, "header-template", "", "Go text/template for the header text; the first line is the title, the rest are metadata lines")
+	rootCmd.PersistentFlags().StringVar(&cfg.HeaderTemplateFile, "header-template-file", "", "Path to a file containing the header template")
+	rootCmd.PersistentFlags().StringVar(&cfg.Crop, "crop", "none", "Crop frames before scaling: auto (remove black bars), none, or WxH:X:Y")
+	rootCmd.PersistentFlags().StringVar(&cfg.ToneMap, "tonemap", "auto", "Tone map HDR sources to SDR: auto (when HDR is detected), on or off")
+	rootCmd.PersistentFlags().StringVar(&cfg.Deinterlace, "deinterlace", "auto", "Deinterlace frames: auto (when the source is interlaced), on or off")
+	rootCmd.PersistentFlags().IntVar(&cfg.VideoStream, "video-stream", -1, "Index of the stream to take frames from (as listed by ffprobe). Defaults to -1 (best video stream, skipping cover art)")
+	rootCmd.PersistentFlags().StringVar(&cfg.Select, "select", "uniform", "How tile times are chosen: uniform (evenly spaced) or chapters (frames from each chapter; rows follow the chapter count)")
+	rootCmd.PersistentFlags().IntVar(&cfg.FramesPerChapter, "frames-per-chapter", 1, "Number of frames taken from each chapter with --select chapters")
+	rootCmd.PersistentFlags().BoolVar(&cfg.ChapterLabels, "chapter-labels", false, "Label the first tile of each chapter with the chapter title")
+
+	// Subtitle caption flags
+	rootCmd.PersistentFlags().StringVar(&cfg.Captions, "captions", "", "Caption each tile with the subtitle shown at its time: auto (default text subtitle track), a subtitle stream index, or an SRT/ASS/VTT file")
+	rootCmd.PersistentFlags().StringVar(&cfg.CaptionPosition, "caption-position", "below", "Caption position: below or over the tile")
+	rootCmd.PersistentFlags().IntVar(&cfg.CaptionSize, "caption-size", 16, "Font size of the captions")
+	rootCmd.PersistentFlags().IntVar(&cfg.CaptionLines, "caption-lines", 2, "Maximum number of lines per caption; longer captions are cut with an ellipsis")
+	rootCmd.PersistentFlags().StringVar(&cfg.BurnSubtitles, "burn-subtitles", "", "Render styled subtitles into the tiles with ffmpeg's subtitles filter: auto (default text subtitle track), a subtitle stream index, or a subtitle file")
 
-	rootCmd.PersistentFlags().StringVar(&cfg.FontFile, "font-file", "", "Path to a .ttf font file for text rendering. If not provided, text will not be rendered.")
+	rootCmd.PersistentFlags().StringVar(&cfg.Font, "font", "", "Installed font to render text with, by name (e.g. \"Noto Sans CJK SC Bold\", see 'fonts list') or path. Defaults to the embedded Go font")
+	rootCmd.PersistentFlags().StringVar(&cfg.FontFile, "font-file", "", "Path to a .ttf/.otf font file for text rendering; append #N to pick face N of a .ttc collection")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.FontFallbacks, "font-fallback", nil, "Fonts used, in order, for characters the main font lacks (e.g. CJK or emoji); names or paths as for --font, repeatable or comma-separated")
 	rootCmd.PersistentFlags().StringVar(&cfg.FontColor, "font-color", "white", "Color of the main font")
 	rootCmd.PersistentFlags().StringVar(&cfg.ShadowColor, "shadow-color", "black", "Color of the text shadow")
-	rootCmd.PersistentFlags().StringVar(&cfg.BackgroundColor, "bg-color", "#222222", "Background color of the montage")
+	rootCmd.PersistentFlags().StringVar(&cfg.BackgroundColor, "bg-color", "#222222", "Background color of the montage; the start color of --bg-gradient")
+	rootCmd.PersistentFlags().StringVar(&cfg.BackgroundGradient, "bg-gradient", "none", "Background gradient from --bg-color to --bg-gradient-to: none, linear or radial (from the center out)")
+	rootCmd.PersistentFlags().StringVar(&cfg.BackgroundGradientTo, "bg-gradient-to", "black", "End color of the background gradient")
+	rootCmd.PersistentFlags().Float64Var(&cfg.BackgroundGradientAngle, "bg-gradient-angle", 180, "Direction of the linear gradient in degrees, as in CSS: 180 runs top to bottom, 90 left to right")
+	rootCmd.PersistentFlags().StringVar(&cfg.BackgroundImage, "bg-image", "", "Image drawn over the background color or gradient")
+	rootCmd.PersistentFlags().StringVar(&cfg.BackgroundImageFit, "bg-image-fit", "cover", "How the background image fills the canvas: cover (scaled and cropped), stretch or tile")
+	rootCmd.PersistentFlags().BoolVar(&cfg.BackgroundFrame, "bg-frame", false, "Use a blurred, darkened frame from the middle of the video as the background")
+	rootCmd.PersistentFlags().IntVar(&cfg.BackgroundBlur, "bg-blur", 30, "Blur radius of the --bg-frame background, in pixels")
+	rootCmd.PersistentFlags().Float64Var(&cfg.BackgroundDim, "bg-dim", 0.5, "How much the --bg-frame background is darkened (0-1)")
+
+	// Tile timestamp flags
+	rootCmd.PersistentFlags().BoolVar(&cfg.ShowTimestamp, "timestamp", true, "Draw the timestamp on each tile")
+	rootCmd.PersistentFlags().StringVar(&cfg.TimestampPosition, "timestamp-position", "bottom-left", "Timestamp position: top-left, top-right, bottom-left, bottom-right, center or below")
+	rootCmd.PersistentFlags().StringVar(&cfg.TimestampFormat, "timestamp-format", "hms", "Timestamp format: hms (HH:MM:SS), hms-ms (with milliseconds), frame (frame number) or timecode (SMPTE, from the embedded start timecode)")
+	rootCmd.PersistentFlags().IntVar(&cfg.TimestampSize, "timestamp-size", 18, "Font size of the tile timestamps")
+	rootCmd.PersistentFlags().StringVar(&cfg.TimestampColor, "timestamp-color", "", "Color of the tile timestamps. Defaults to --font-color")
+	rootCmd.PersistentFlags().StringVar(&cfg.TimestampBgColor, "timestamp-bg-color", "black", "Color of the box behind the tile timestamps")
+	rootCmd.PersistentFlags().Float64Var(&cfg.TimestampBgOpacity, "timestamp-bg-opacity", 0, "Opacity of the box behind the tile timestamps (0-1, 0 draws no box)")
 
 	// New flags for quality and aesthetics
 	rootCmd.PersistentFlags().IntVar(&cfg.JpegQuality, "jpeg-quality", 2, "JPEG quality for the output image (1-31, lower is better)")
+	rootCmd.PersistentFlags().StringVar(&cfg.AudioVisual, "audio-visual", "spectrogram", "Visualization for audio-only inputs: spectrogram or waveform")
+
+	// Movie barcode flags
+	rootCmd.PersistentFlags().StringVar(&cfg.Barcode, "barcode", "none", "Where to draw the movie barcode band: none, header or footer")
+	rootCmd.PersistentFlags().IntVar(&cfg.BarcodeHeight, "barcode-height", 60, "Height of the movie barcode band")
+	rootCmd.PersistentFlags().IntVar(&cfg.BarcodeFrames, "barcode-frames", 300, "Number of frames sampled for the movie barcode (keyframes only, so sparse keyframes give fewer)")
+	rootCmd.PersistentFlags().StringVar(&cfg.BarcodeMode, "barcode-mode", "average", "How each barcode frame is reduced to a color: average or dominant")
+	rootCmd.PersistentFlags().StringVar(&cfg.BarcodeOutput, "barcode-output", "", "Also save the movie barcode as a standalone image (.png or .jpg)")
+
+	// Palette and sidecar flags
+	rootCmd.PersistentFlags().IntVar(&cfg.Palette, "palette", 0, "Number of dominant colors to extract from the frames (0 disables)")
+	rootCmd.PersistentFlags().BoolVar(&cfg.PaletteHeader, "palette-header", false, "Draw the dominant color swatches with hex codes in the header; needs --palette")
+	rootCmd.PersistentFlags().StringVar(&cfg.SidecarPath, "sidecar", "", "Write a JSON sidecar with video info, tile timestamps and palette. Use '-' for stdout.")
+
+	// Tile decoration flags
+	rootCmd.PersistentFlags().IntVar(&cfg.TileBorder, "tile-border", 0, "Width of the border drawn inside each tile (0 for none)")
+	rootCmd.PersistentFlags().StringVar(&cfg.TileBorderColor, "tile-border-color", "white", "Color of the tile borders")
+	rootCmd.PersistentFlags().IntVar(&cfg.TileRadius, "tile-radius", 0, "Corner radius of the tiles")
+	rootCmd.PersistentFlags().IntVar(&cfg.TileShadowOffset, "tile-shadow-offset", 0, "Distance of the tile drop shadows down and to the right (0 with no blur for none)")
+	rootCmd.PersistentFlags().IntVar(&cfg.TileShadowBlur, "tile-shadow-blur", 0, "How far the tile drop shadows are blurred, in pixels")
+	rootCmd.PersistentFlags().StringVar(&cfg.TileShadowColor, "tile-shadow-color", "black", "Color of the tile drop shadows")
+	rootCmd.PersistentFlags().Float64Var(&cfg.TileShadowOpacity, "tile-shadow-opacity", 0.6, "Opacity of the tile drop shadows (0-1)")
+
+	// Logo and watermark flags
+	rootCmd.PersistentFlags().StringVar(&cfg.Logo, "logo", "", "Image (PNG with alpha) to place in the header")
+	rootCmd.PersistentFlags().StringVar(&cfg.LogoPosition, "logo-position", "right", "Logo position in the header: left or right")
+	rootCmd.PersistentFlags().Float64Var(&cfg.LogoScale, "logo-scale", 1, "Scale of the logo relative to its image size; it is shrunk further to fit a fixed --header height")
+	rootCmd.PersistentFlags().Float64Var(&cfg.LogoOpacity, "logo-opacity", 1, "Opacity of the logo (0-1)")
+	rootCmd.PersistentFlags().StringVar(&cfg.Watermark, "watermark", "", "Image (PNG with alpha) to stamp over the tiles or the whole canvas")
+	rootCmd.PersistentFlags().StringVar(&cfg.WatermarkTarget, "watermark-target", "tiles", "What the watermark covers: tiles (each tile) or canvas (the whole sheet)")
+	rootCmd.PersistentFlags().StringVar(&cfg.WatermarkPosition, "watermark-position", "bottom-right", "Watermark position: top-left, top-right, bottom-left, bottom-right, center or tiled (repeated across the area)")
+	rootCmd.PersistentFlags().Float64Var(&cfg.WatermarkScale, "watermark-scale", 0.2, "Width of the watermark relative to the tile or canvas width (0-1)")
+	rootCmd.PersistentFlags().Float64Var(&cfg.WatermarkOpacity, "watermark-opacity", 0.4, "Opacity of the watermark (0-1)")
 
 	// Paths for external binaries
 	rootCmd.PersistentFlags().StringVar(&cfg.FfmpegPath, "ffmpeg-path", "ffmpeg", "Path to the ffmpeg executable")
@@ -137,7 +216,45 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&cfg.ShowFfmpegLog, "show-ffmpeg-log", true, "Show real-time output from the ffmpeg process")
 }
 
+// loadConfig lays the preset and the config file under the flags given on
+// the command line.
+func loadConfig(cmd *cobra.Command, cfg *config.Config) error {
+	// Settings are layered: flag defaults, then the preset, then the
+	// config file, then the flags given on the command line.
+	preset := cfg.Preset
+	if configPath != "" {
+		fileCfg, err := config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config file: %w", err)
+		}
+		// The config file may name the preset it builds on.
+		if !cmd.Flags().Changed("preset") && fileCfg.Preset != "" {
+			preset = fileCfg.Preset
+		}
+	}
+	if preset != "" || configPath != "" {
+		layered := *cfg
+		if preset != "" {
+			data, err := config.ReadPreset(preset)
+			if err != nil {
+				return fmt.Errorf("failed to load preset: %w", err)
+			}
+			if err := layered.Apply(data); err != nil {
+				return fmt.Errorf("failed to load preset %s: %w", preset, err)
+			}
+		}
+		if configPath != "" {
+			if err := layered.ApplyFile(configPath); err != nil {
+				return fmt.Errorf("failed to load config file: %w", err)
+			}
+		}
+		mergeConfig(cmd, cfg, &layered)
+	}
+	return nil
+}
+
 // mergeConfig applies values from fileCfg into cfg for flags that were not explicitly set on CLI.
+// fileCfg holds the flag defaults overlaid with the preset and config file.
 func mergeConfig(cmd *cobra.Command, cfg *config.Config, fileCfg *config.Config) {
 	set := func(name string) bool {
 		changed, _ := cmd.Flags().GetBool("--dummy")
@@ -148,6 +265,12 @@ func mergeConfig(cmd *cobra.Command, cfg *config.Config, fileCfg *config.Config)
 	if !set("output") {
 		cfg.OutputPath = fileCfg.OutputPath
 	}
+	if !set("preset") {
+		cfg.Preset = fileCfg.Preset
+	}
+	if !set("format") {
+		cfg.OutputFormat = fileCfg.OutputFormat
+	}
 	if !set("columns") {
 		cfg.Columns = fileCfg.Columns
 	}
@@ -166,13 +289,76 @@ func mergeConfig(cmd *cobra.Command, cfg *config.Config, fileCfg *config.Config)
 	if !set("margin") {
 		cfg.Margin = fileCfg.Margin
 	}
+	if !set("canvas-width") {
+		cfg.CanvasWidth = fileCfg.CanvasWidth
+	}
+	if !set("interval") {
+		cfg.Interval = fileCfg.Interval
+	}
+	if !set("count") {
+		cfg.TileCount = fileCfg.TileCount
+	}
 	if !set("header") {
 		cfg.HeaderHeight = fileCfg.HeaderHeight
 	}
+	if !set("header-style") {
+		cfg.HeaderStyle = fileCfg.HeaderStyle
+	}
+	if !set("header-position") {
+		cfg.HeaderPosition = fileCfg.HeaderPosition
+	}
+	if !set("header-template") {
+		cfg.HeaderTemplate = fileCfg.HeaderTemplate
+	}
+	if !set("header-template-file") {
+		cfg.HeaderTemplateFile = fileCfg.HeaderTemplateFile
+	}
+	if !set("crop") {
+		cfg.Crop = fileCfg.Crop
+	}
+	if !set("tonemap") {
+		cfg.ToneMap = fileCfg.ToneMap
+	}
+	if !set("deinterlace") {
+		cfg.Deinterlace = fileCfg.Deinterlace
+	}
+	if !set("video-stream") {
+		cfg.VideoStream = fileCfg.VideoStream
+	}
+	if !set("select") {
+		cfg.Select = fileCfg.Select
+	}
+	if !set("frames-per-chapter") {
+		cfg.FramesPerChapter = fileCfg.FramesPerChapter
+	}
+	if !set("chapter-labels") {
+		cfg.ChapterLabels = fileCfg.ChapterLabels
+	}
+	if !set("captions") {
+		cfg.Captions = fileCfg.Captions
+	}
+	if !set("caption-position") {
+		cfg.CaptionPosition = fileCfg.CaptionPosition
+	}
+	if !set("caption-size") {
+		cfg.CaptionSize = fileCfg.CaptionSize
+	}
+	if !set("caption-lines") {
+		cfg.CaptionLines = fileCfg.CaptionLines
+	}
+	if !set("burn-subtitles") {
+		cfg.BurnSubtitles = fileCfg.BurnSubtitles
+	}
 
+	if !set("font") {
+		cfg.Font = fileCfg.Font
+	}
 	if !set("font-file") {
 		cfg.FontFile = fileCfg.FontFile
 	}
+	if !set("font-fallback") {
+		cfg.FontFallbacks = fileCfg.FontFallbacks
+	}
 	if !set("font-color") {
 		cfg.FontColor = fileCfg.FontColor
 	}
@@ -182,10 +368,135 @@ func mergeConfig(cmd *cobra.Command, cfg *config.Config, fileCfg *config.Config)
 	if !set("bg-color") {
 		cfg.BackgroundColor = fileCfg.BackgroundColor
 	}
+	if !set("bg-gradient") {
+		cfg.BackgroundGradient = fileCfg.BackgroundGradient
+	}
+	if !set("bg-gradient-to") {
+		cfg.BackgroundGradientTo = fileCfg.BackgroundGradientTo
+	}
+	if !set("bg-gradient-angle") {
+		cfg.BackgroundGradientAngle = fileCfg.BackgroundGradientAngle
+	}
+	if !set("bg-image") {
+		cfg.BackgroundImage = fileCfg.BackgroundImage
+	}
+	if !set("bg-image-fit") {
+		cfg.BackgroundImageFit = fileCfg.BackgroundImageFit
+	}
+	if !set("bg-frame") {
+		cfg.BackgroundFrame = fileCfg.BackgroundFrame
+	}
+	if !set("bg-blur") {
+		cfg.BackgroundBlur = fileCfg.BackgroundBlur
+	}
+	if !set("bg-dim") {
+		cfg.BackgroundDim = fileCfg.BackgroundDim
+	}
+
+	if !set("timestamp") {
+		cfg.ShowTimestamp = fileCfg.ShowTimestamp
+	}
+	if !set("timestamp-position") {
+		cfg.TimestampPosition = fileCfg.TimestampPosition
+	}
+	if !set("timestamp-format") {
+		cfg.TimestampFormat = fileCfg.TimestampFormat
+	}
+	if !set("timestamp-size") {
+		cfg.TimestampSize = fileCfg.TimestampSize
+	}
+	if !set("timestamp-color") {
+		cfg.TimestampColor = fileCfg.TimestampColor
+	}
+	if !set("timestamp-bg-color") {
+		cfg.TimestampBgColor = fileCfg.TimestampBgColor
+	}
+	if !set("timestamp-bg-opacity") {
+		cfg.TimestampBgOpacity = fileCfg.TimestampBgOpacity
+	}
 
 	if !set("jpeg-quality") {
 		cfg.JpegQuality = fileCfg.JpegQuality
 	}
+	if !set("audio-visual") {
+		cfg.AudioVisual = fileCfg.AudioVisual
+	}
+
+	if !set("barcode") {
+		cfg.Barcode = fileCfg.Barcode
+	}
+	if !set("barcode-height") {
+		cfg.BarcodeHeight = fileCfg.BarcodeHeight
+	}
+	if !set("barcode-frames") {
+		cfg.BarcodeFrames = fileCfg.BarcodeFrames
+	}
+	if !set("barcode-mode") {
+		cfg.BarcodeMode = fileCfg.BarcodeMode
+	}
+	if !set("barcode-output") {
+		cfg.BarcodeOutput = fileCfg.BarcodeOutput
+	}
+
+	if !set("palette") {
+		cfg.Palette = fileCfg.Palette
+	}
+	if !set("palette-header") {
+		cfg.PaletteHeader = fileCfg.PaletteHeader
+	}
+	if !set("sidecar") {
+		cfg.SidecarPath = fileCfg.SidecarPath
+	}
+
+	if !set("tile-border") {
+		cfg.TileBorder = fileCfg.TileBorder
+	}
+	if !set("tile-border-color") {
+		cfg.TileBorderColor = fileCfg.TileBorderColor
+	}
+	if !set("tile-radius") {
+		cfg.TileRadius = fileCfg.TileRadius
+	}
+	if !set("tile-shadow-offset") {
+		cfg.TileShadowOffset = fileCfg.TileShadowOffset
+	}
+	if !set("tile-shadow-blur") {
+		cfg.TileShadowBlur = fileCfg.TileShadowBlur
+	}
+	if !set("tile-shadow-color") {
+		cfg.TileShadowColor = fileCfg.TileShadowColor
+	}
+	if !set("tile-shadow-opacity") {
+		cfg.TileShadowOpacity = fileCfg.TileShadowOpacity
+	}
+
+	if !set("logo") {
+		cfg.Logo = fileCfg.Logo
+	}
+	if !set("logo-position") {
+		cfg.LogoPosition = fileCfg.LogoPosition
+	}
+	if !set("logo-scale") {
+		cfg.LogoScale = fileCfg.LogoScale
+	}
+	if !set("logo-opacity") {
+		cfg.LogoOpacity = fileCfg.LogoOpacity
+	}
+	if !set("watermark") {
+		cfg.Watermark = fileCfg.Watermark
+	}
+	if !set("watermark-target") {
+		cfg.WatermarkTarget = fileCfg.WatermarkTarget
+	}
+	if !set("watermark-position") {
+		cfg.WatermarkPosition = fileCfg.WatermarkPosition
+	}
+	if !set("watermark-scale") {
+		cfg.WatermarkScale = fileCfg.WatermarkScale
+	}
+	if !set("watermark-opacity") {
+		cfg.WatermarkOpacity = fileCfg.WatermarkOpacity
+	}
 
 	if !set("ffmpeg-path") {
 		cfg.FfmpegPath = fileCfg.FfmpegPath
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	// Keep user presets out of the way of the built-in one.
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "preset: dark\nmargin: 30\nbackground_color: navy\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rootCmd.ParseFlags([]string{"--config", path, "--bg-color", "red"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(rootCmd, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		// Neither the preset nor the file sets the columns.
+		{"flag default", cfg.Columns, 4},
+		// The dark preset sets the padding and the font color.
+		{"preset over default", cfg.Padding, 10},
+		{"preset over default", cfg.FontColor, "#f2f2f2"},
+		// The preset and the file both set the margin.
+		{"file over preset", cfg.Margin, 30},
+		// The preset, the file and the command line set the background.
+		{"flag over file", cfg.BackgroundColor, "red"},
+		{"preset named by file", cfg.Preset, "dark"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,8 @@ go 1.24.3
 require (
 	github.com/fogleman/gg v1.3.0
 	github.com/spf13/cobra v1.10.1
+	golang.org/x/image v0.32.0
+	golang.org/x/text v0.30.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -12,5 +14,4 @@ require (
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
-	golang.org/x/image v0.32.0 // indirect
 )
@@ -0,0 +1,228 @@
+// Package csscolor parses colors written as in CSS.
+package csscolor
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Parse parses a CSS color: a named color, "transparent", #RGB, #RGBA,
+// #RRGGBB, #RRGGBBAA, rgb()/rgba() or hsl()/hsla(), with the components
+// separated by commas or by spaces ("rgb(0 0 0 / 50%)"). Six or eight hex
+// digits without the "#" are accepted too, as "#" starts a comment in
+// unquoted YAML.
+func Parse(s string) (color.NRGBA, error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+	if str == "" {
+		return color.NRGBA{}, fmt.Errorf("empty color")
+	}
+	if str == "transparent" {
+		return color.NRGBA{}, nil
+	}
+	if v, ok := names[str]; ok {
+		return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+	}
+	if hex, ok := strings.CutPrefix(str, "#"); ok {
+		return parseHex(hex, s)
+	}
+	if name, args, ok := strings.Cut(str, "("); ok && strings.HasSuffix(args, ")") {
+		parts := splitArgs(strings.TrimSuffix(args, ")"))
+		switch strings.TrimSpace(name) {
+		case "rgb", "rgba":
+			return parseRGB(parts, s)
+		case "hsl", "hsla":
+			return parseHSL(parts, s)
+		}
+		return color.NRGBA{}, fmt.Errorf("unsupported color function in %q (use rgb(), rgba(), hsl() or hsla())", s)
+	}
+	if (len(str) == 6 || len(str) == 8) && isHex(str) {
+		return parseHex(str, s)
+	}
+	return color.NRGBA{}, fmt.Errorf("unknown color %q", s)
+}
+
+// parseHex parses the hex digits of #RGB, #RGBA, #RRGGBB or #RRGGBBAA.
+func parseHex(hex, orig string) (color.NRGBA, error) {
+	if !isHex(hex) {
+		return color.NRGBA{}, fmt.Errorf("invalid hex color %q", orig)
+	}
+	// Short forms repeat each digit: #f80 is #ff8800.
+	if len(hex) == 3 || len(hex) == 4 {
+		var b strings.Builder
+		for _, r := range hex {
+			b.WriteRune(r)
+			b.WriteRune(r)
+		}
+		hex = b.String()
+	}
+	switch len(hex) {
+	case 6:
+		hex += "ff"
+	case 8:
+	default:
+		return color.NRGBA{}, fmt.Errorf("invalid hex color %q (use 3, 4, 6 or 8 digits)", orig)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid hex color %q", orig)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// splitArgs splits the arguments of a color function, either "r, g, b, a"
+// or "r g b / a".
+func splitArgs(args string) []string {
+	var parts []string
+	if strings.Contains(args, ",") {
+		parts = strings.Split(args, ",")
+	} else {
+		main, alpha, hasAlpha := strings.Cut(args, "/")
+		parts = strings.Fields(main)
+		if hasAlpha {
+			parts = append(parts, alpha)
+		}
+	}
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// parseRGB parses the arguments of rgb() and rgba(): three channels from 0
+// to 255 or percentages, and an optional alpha.
+func parseRGB(parts []string, orig string) (color.NRGBA, error) {
+	if len(parts) != 3 && len(parts) != 4 {
+		return color.NRGBA{}, fmt.Errorf("color %q needs 3 channels and an optional alpha", orig)
+	}
+	var rgb [3]uint8
+	for i := range rgb {
+		v, percent, err := parseNumber(parts[i])
+		if err != nil {
+			return color.NRGBA{}, fmt.Errorf("invalid channel %q in color %q", parts[i], orig)
+		}
+		if percent {
+			v /= 100
+		} else {
+			v /= 255
+		}
+		rgb[i] = toByte(v)
+	}
+	alpha, err := parseAlpha(parts, orig)
+	if err != nil {
+		return color.NRGBA{}, err
+	}
+	return color.NRGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: alpha}, nil
+}
+
+// parseHSL parses the arguments of hsl() and hsla(): a hue in degrees (or
+// with a deg, rad, grad or turn unit), saturation and lightness in percent,
+// and an optional alpha.
+func parseHSL(parts []string, orig string) (color.NRGBA, error) {
+	if len(parts) != 3 && len(parts) != 4 {
+		return color.NRGBA{}, fmt.Errorf("color %q needs hue, saturation, lightness and an optional alpha", orig)
+	}
+	hue, err := parseHue(parts[0])
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid hue %q in color %q", parts[0], orig)
+	}
+	var sl [2]float64
+	for i := range sl {
+		v, _, err := parseNumber(parts[i+1])
+		if err != nil {
+			return color.NRGBA{}, fmt.Errorf("invalid percentage %q in color %q", parts[i+1], orig)
+		}
+		sl[i] = math.Min(math.Max(v/100, 0), 1)
+	}
+	alpha, err := parseAlpha(parts, orig)
+	if err != nil {
+		return color.NRGBA{}, err
+	}
+
+	r, g, b := hslToRGB(hue, sl[0], sl[1])
+	return color.NRGBA{R: toByte(r), G: toByte(g), B: toByte(b), A: alpha}, nil
+}
+
+// parseAlpha returns the fourth component as an alpha byte, or opaque if
+// there is none.
+func parseAlpha(parts []string, orig string) (uint8, error) {
+	if len(parts) < 4 {
+		return 255, nil
+	}
+	v, percent, err := parseNumber(parts[3])
+	if err != nil {
+		return 0, fmt.Errorf("invalid alpha %q in color %q", parts[3], orig)
+	}
+	if percent {
+		v /= 100
+	}
+	return toByte(v), nil
+}
+
+// parseNumber parses a number, reporting whether it was a percentage.
+func parseNumber(s string) (float64, bool, error) {
+	num, percent := strings.CutSuffix(s, "%")
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false, fmt.Errorf("invalid number %q", s)
+	}
+	return v, percent, nil
+}
+
+// parseHue parses a hue angle and returns it in degrees.
+func parseHue(s string) (float64, error) {
+	units := []struct {
+		suffix  string
+		degrees float64
+	}{
+		// "grad" and "rad" both end in "ad", so the longer suffix is tried
+		// first.
+		{"grad", 360.0 / 400},
+		{"turn", 360},
+		{"rad", 180 / math.Pi},
+		{"deg", 1},
+	}
+	factor := 1.0
+	for _, u := range units {
+		if num, ok := strings.CutSuffix(s, u.suffix); ok {
+			s, factor = num, u.degrees
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid hue %q", s)
+	}
+	return v * factor, nil
+}
+
+// hslToRGB converts a hue in degrees and saturation and lightness from 0
+// to 1 into red, green and blue from 0 to 1.
+func hslToRGB(hue, s, l float64) (float64, float64, float64) {
+	hue = math.Mod(hue, 360)
+	if hue < 0 {
+		hue += 360
+	}
+	channel := func(n float64) float64 {
+		k := math.Mod(n+hue/30, 12)
+		a := s * math.Min(l, 1-l)
+		return l - a*math.Max(-1, math.Min(math.Min(k-3, 9-k), 1))
+	}
+	return channel(0), channel(8), channel(4)
+}
+
+// toByte converts a value from 0 to 1 into a byte, clamping it first.
+func toByte(v float64) uint8 {
+	return uint8(math.Round(math.Min(math.Max(v, 0), 1) * 255))
+}
@@ -0,0 +1,93 @@
+package csscolor
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want color.NRGBA
+	}{
+		// Names are case-insensitive and may be padded.
+		{"red", color.NRGBA{255, 0, 0, 255}},
+		{"RebeccaPurple", color.NRGBA{0x66, 0x33, 0x99, 255}},
+		{" navy ", color.NRGBA{0, 0, 128, 255}},
+		{"transparent", color.NRGBA{0, 0, 0, 0}},
+
+		// Hex forms; the short ones repeat each digit.
+		{"#f80", color.NRGBA{0xff, 0x88, 0x00, 255}},
+		{"#f808", color.NRGBA{0xff, 0x88, 0x00, 0x88}},
+		{"#FF8800", color.NRGBA{0xff, 0x88, 0x00, 255}},
+		{"#ff880080", color.NRGBA{0xff, 0x88, 0x00, 0x80}},
+		{"ff8800", color.NRGBA{0xff, 0x88, 0x00, 255}},
+		{"ff880080", color.NRGBA{0xff, 0x88, 0x00, 0x80}},
+
+		// rgb() and rgba() with commas or spaces.
+		{"rgb(255, 0, 0)", color.NRGBA{255, 0, 0, 255}},
+		{"rgba(255,0,0,0.5)", color.NRGBA{255, 0, 0, 128}},
+		{"rgb(0 0 0 / 50%)", color.NRGBA{0, 0, 0, 128}},
+		{"rgba(10 20 30)", color.NRGBA{10, 20, 30, 255}},
+		{"rgb(100%, 50%, 0%)", color.NRGBA{255, 128, 0, 255}},
+		{"RGB( 1 , 2 , 3 )", color.NRGBA{1, 2, 3, 255}},
+		// Out of range values are clamped.
+		{"rgb(300, -20, 0)", color.NRGBA{255, 0, 0, 255}},
+		{"rgba(0, 0, 0, 2)", color.NRGBA{0, 0, 0, 255}},
+		{"rgba(0, 0, 0, -1)", color.NRGBA{0, 0, 0, 0}},
+
+		// hsl() and hsla() with hue units.
+		{"hsl(0, 100%, 50%)", color.NRGBA{255, 0, 0, 255}},
+		{"hsl(120deg 100% 25%)", color.NRGBA{0, 128, 0, 255}},
+		{"hsl(240, 100%, 50%)", color.NRGBA{0, 0, 255, 255}},
+		{"hsl(-120, 100%, 50%)", color.NRGBA{0, 0, 255, 255}},
+		{"hsl(480, 100%, 50%)", color.NRGBA{0, 255, 0, 255}},
+		{"hsl(0.5turn, 100%, 50%)", color.NRGBA{0, 255, 255, 255}},
+		{"hsl(200grad, 100%, 50%)", color.NRGBA{0, 255, 255, 255}},
+		{"hsl(3.14159265rad, 100%, 50%)", color.NRGBA{0, 255, 255, 255}},
+		{"hsl(0, 0%, 50%)", color.NRGBA{128, 128, 128, 255}},
+		{"hsla(0, 0%, 100%, 0.25)", color.NRGBA{255, 255, 255, 64}},
+		{"hsl(0 100% 50% / 10%)", color.NRGBA{255, 0, 0, 26}},
+		// Saturation and lightness are clamped.
+		{"hsl(0, 150%, 120%)", color.NRGBA{255, 255, 255, 255}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"   ",
+		"notacolor",
+		"#",
+		"#12",
+		"#12345",
+		"#1234567",
+		"#ggg",
+		"12345",
+		"fff",
+		"rgb(1, 2)",
+		"rgb(1, 2, 3, 4, 5)",
+		"rgb(a, b, c)",
+		"rgb(1, 2, 3",
+		"rgb(1, 2, 3, x)",
+		"rgb(NaN, 0, 0)",
+		"foo(1, 2, 3)",
+		"hsl(x, 1%, 1%)",
+		"hsl(0, a%, 1%)",
+		"hsl(0deg)",
+	} {
+		if c, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %v, want an error", in, c)
+		}
+	}
+}
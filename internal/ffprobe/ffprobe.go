@@ -4,52 +4,174 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os/exec"
+	"regexp"
 	"strconv"
+	"strings"
 )
 
 // VideoInfo holds simplified, essential video metadata.
 type VideoInfo struct {
-	Path         string
-	Duration     float64
-	Width        int
-	Height       int
-	FileSize     int64
-	VideoCodec   string
-	AudioCodec   string
-	BitRate      string
-	AvgFrameRate string
+	Path         string  `json:"path"`
+	Duration     float64 `json:"duration"`
+	Width        int     `json:"width,omitempty"`
+	Height       int     `json:"height,omitempty"`
+	FileSize     int64   `json:"file_size"`
+	VideoCodec   string  `json:"video_codec,omitempty"`
+	AudioCodec   string  `json:"audio_codec,omitempty"`
+	BitRate      string  `json:"bit_rate,omitempty"`
+	AvgFrameRate string  `json:"avg_frame_rate,omitempty"`
+	SampleRate   int     `json:"sample_rate,omitempty"`
+	Channels     int     `json:"channels,omitempty"`
+	// VideoStreamIndex is the container index of the stream frames are
+	// taken from, for use with ffmpeg's -map.
+	VideoStreamIndex int `json:"video_stream_index"`
+	// Rotation is the clockwise display rotation in degrees (0, 90, 180, 270).
+	Rotation           int    `json:"rotation,omitempty"`
+	SampleAspectRatio  string `json:"sample_aspect_ratio,omitempty"`
+	DisplayAspectRatio string `json:"display_aspect_ratio,omitempty"`
+	// DisplayWidth and DisplayHeight are the size the picture is meant to be
+	// shown at, after applying the sample aspect ratio and rotation.
+	DisplayWidth   int    `json:"display_width,omitempty"`
+	DisplayHeight  int    `json:"display_height,omitempty"`
+	ColorSpace     string `json:"color_space,omitempty"`
+	ColorTransfer  string `json:"color_transfer,omitempty"`
+	ColorPrimaries string `json:"color_primaries,omitempty"`
+	// HDRFormat is "HDR10", "HLG" or "Dolby Vision", empty for SDR.
+	HDRFormat string `json:"hdr_format,omitempty"`
+	// FieldOrder is ffprobe's field_order, e.g. "progressive" or "tt".
+	FieldOrder   string `json:"field_order,omitempty"`
+	Interlaced   bool   `json:"interlaced,omitempty"`
+	VideoProfile string `json:"video_profile,omitempty"`
+	VideoLevel   string `json:"video_level,omitempty"`
+	PixelFormat  string `json:"pixel_format,omitempty"`
+	BitDepth     int    `json:"bit_depth,omitempty"`
+	VideoBitRate int64  `json:"video_bit_rate,omitempty"`
+	// AudioTracks and SubtitleTracks list every stream of that type in
+	// container order. AudioCodec, SampleRate and Channels above describe
+	// the first audio track.
+	AudioTracks    []AudioTrack    `json:"audio_tracks,omitempty"`
+	SubtitleTracks []SubtitleTrack `json:"subtitle_tracks,omitempty"`
+	FormatName     string          `json:"format_name,omitempty"`
+	FormatLongName string          `json:"format_long_name,omitempty"`
+	CreationTime   string          `json:"creation_time,omitempty"`
+	// StartTime is the container's first timestamp in seconds, non-zero
+	// in MPEG-TS and some other formats.
+	StartTime float64 `json:"start_time,omitempty"`
+	// StartTimecode is the embedded SMPTE start timecode, e.g. "01:00:00:00",
+	// or "01:00:00;00" for drop-frame.
+	StartTimecode string    `json:"start_timecode,omitempty"`
+	Chapters      []Chapter `json:"chapters,omitempty"`
+	// AudioOnly is set when the input has no video stream but does have
+	// an audio stream, e.g. music or podcast files.
+	AudioOnly bool `json:"audio_only,omitempty"`
+}
+
+// Chapter is a chapter marker of the container, with times in seconds.
+type Chapter struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Title string  `json:"title,omitempty"`
+}
+
+// AudioTrack describes one audio stream of the container.
+type AudioTrack struct {
+	Index         int    `json:"index"`
+	Codec         string `json:"codec"`
+	Profile       string `json:"profile,omitempty"`
+	Channels      int    `json:"channels,omitempty"`
+	ChannelLayout string `json:"channel_layout,omitempty"`
+	SampleRate    int    `json:"sample_rate,omitempty"`
+	BitRate       int64  `json:"bit_rate,omitempty"`
+	Language      string `json:"language,omitempty"`
+	Title         string `json:"title,omitempty"`
+	Default       bool   `json:"default,omitempty"`
+}
+
+// SubtitleTrack describes one subtitle stream of the container.
+type SubtitleTrack struct {
+	Index    int    `json:"index"`
+	Codec    string `json:"codec"`
+	Language string `json:"language,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Default  bool   `json:"default,omitempty"`
+	Forced   bool   `json:"forced,omitempty"`
 }
 
 // ffprobeOutput matches the JSON structure from the ffprobe command.
 type ffprobeOutput struct {
-	Streams []ffprobeStream `json:"streams"`
-	Format  ffprobeFormat   `json:"format"`
+	Streams  []ffprobeStream  `json:"streams"`
+	Format   ffprobeFormat    `json:"format"`
+	Chapters []ffprobeChapter `json:"chapters"`
 }
 
 type ffprobeStream struct {
+	Index        int    `json:"index"`
 	CodecType    string `json:"codec_type"`
 	CodecName    string `json:"codec_name"`
 	Width        int    `json:"width"`
 	Height       int    `json:"height"`
 	AvgFrameRate string `json:"avg_frame_rate"`
+	SampleRate   string `json:"sample_rate"`
+	Channels     int    `json:"channels"`
+
+	Profile          string `json:"profile"`
+	Level            int    `json:"level"`
+	PixFmt           string `json:"pix_fmt"`
+	BitsPerRawSample string `json:"bits_per_raw_sample"`
+	BitRate          string `json:"bit_rate"`
+	ChannelLayout    string `json:"channel_layout"`
+
+	SampleAspectRatio  string             `json:"sample_aspect_ratio"`
+	DisplayAspectRatio string             `json:"display_aspect_ratio"`
+	ColorSpace         string             `json:"color_space"`
+	ColorTransfer      string             `json:"color_transfer"`
+	ColorPrimaries     string             `json:"color_primaries"`
+	FieldOrder         string             `json:"field_order"`
+	Tags               map[string]string  `json:"tags"`
+	SideDataList       []ffprobeSideData  `json:"side_data_list"`
+	Disposition        ffprobeDisposition `json:"disposition"`
+}
+
+type ffprobeDisposition struct {
+	Default     int `json:"default"`
+	Forced      int `json:"forced"`
+	AttachedPic int `json:"attached_pic"`
+}
+
+type ffprobeSideData struct {
+	SideDataType string  `json:"side_data_type"`
+	Rotation     float64 `json:"rotation"`
+}
+
+type ffprobeChapter struct {
+	StartTime string            `json:"start_time"`
+	EndTime   string            `json:"end_time"`
+	Tags      map[string]string `json:"tags"`
 }
 
 type ffprobeFormat struct {
-	Duration string            `json:"duration"`
-	Size     string            `json:"size"`
-	Filename string            `json:"filename"`
-	BitRate  string            `json:"bit_rate"`
-	Tags     map[string]string `json:"tags"`
+	FormatName     string `json:"format_name"`
+	FormatLongName string `json:"format_long_name"`
+
+	StartTime string            `json:"start_time"`
+	Duration  string            `json:"duration"`
+	Size      string            `json:"size"`
+	Filename  string            `json:"filename"`
+	BitRate   string            `json:"bit_rate"`
+	Tags      map[string]string `json:"tags"`
 }
 
-// GetVideoInfo executes ffprobe to get video metadata.
-func GetVideoInfo(path string, ffprobePath string) (*VideoInfo, error) {
+// GetVideoInfo executes ffprobe to get video metadata. videoStream is the
+// index of the stream to take frames from, or -1 to pick the best one.
+func GetVideoInfo(path string, ffprobePath string, videoStream int) (*VideoInfo, error) {
 	cmd := exec.Command(ffprobePath,
 		"-v", "error",
 		"-print_format", "json",
 		"-show_format",
 		"-show_streams",
+		"-show_chapters",
 		path,
 	)
 
@@ -69,36 +191,302 @@ func GetVideoInfo(path string, ffprobePath string) (*VideoInfo, error) {
 	}
 
 	info := &VideoInfo{
-		Path:    ffData.Format.Filename,
-		BitRate: ffData.Format.BitRate,
+		Path:           ffData.Format.Filename,
+		BitRate:        ffData.Format.BitRate,
+		FormatName:     ffData.Format.FormatName,
+		FormatLongName: ffData.Format.FormatLongName,
+		CreationTime:   ffData.Format.Tags["creation_time"],
+		StartTimecode:  ffData.Format.Tags["timecode"],
 	}
 
 	if duration, err := strconv.ParseFloat(ffData.Format.Duration, 64); err == nil {
 		info.Duration = duration
 	}
+	if start, err := strconv.ParseFloat(ffData.Format.StartTime, 64); err == nil {
+		info.StartTime = start
+	}
 	if size, err := strconv.ParseInt(ffData.Format.Size, 10, 64); err == nil {
 		info.FileSize = size
 	}
 
+	for _, ch := range ffData.Chapters {
+		start, err := strconv.ParseFloat(ch.StartTime, 64)
+		if err != nil {
+			continue
+		}
+		end, err := strconv.ParseFloat(ch.EndTime, 64)
+		if err != nil {
+			continue
+		}
+		info.Chapters = append(info.Chapters, Chapter{Start: start, End: end, Title: ch.Tags["title"]})
+	}
+
+	video, err := selectVideoStream(ffData.Streams, videoStream)
+	if err != nil {
+		return nil, err
+	}
+	if video != nil {
+		info.setVideoStream(*video)
+	}
+
+	// The start timecode may also live on the video stream or on a separate
+	// timecode data track (e.g. QuickTime tmcd).
+	if info.StartTimecode == "" && video != nil {
+		info.StartTimecode = video.Tags["timecode"]
+	}
+	for _, stream := range ffData.Streams {
+		if info.StartTimecode == "" && stream.CodecType == "data" {
+			info.StartTimecode = stream.Tags["timecode"]
+		}
+	}
+
 	for _, stream := range ffData.Streams {
 		switch stream.CodecType {
-		case "video":
-			if info.VideoCodec == "" { // Take the first video stream
-				info.Width = stream.Width
-				info.Height = stream.Height
-				info.VideoCodec = stream.CodecName
-				info.AvgFrameRate = stream.AvgFrameRate
-			}
 		case "audio":
+			track := AudioTrack{
+				Index:         stream.Index,
+				Codec:         stream.CodecName,
+				Profile:       stream.Profile,
+				Channels:      stream.Channels,
+				ChannelLayout: stream.ChannelLayout,
+				Language:      stream.Tags["language"],
+				Title:         stream.Tags["title"],
+				Default:       stream.Disposition.Default != 0,
+			}
+			track.SampleRate, _ = strconv.Atoi(stream.SampleRate)
+			track.BitRate, _ = strconv.ParseInt(stream.BitRate, 10, 64)
+			info.AudioTracks = append(info.AudioTracks, track)
+
 			if info.AudioCodec == "" { // Take the first audio stream
-				info.AudioCodec = stream.CodecName
+				info.AudioCodec = track.Codec
+				info.Channels = track.Channels
+				info.SampleRate = track.SampleRate
 			}
+		case "subtitle":
+			info.SubtitleTracks = append(info.SubtitleTracks, SubtitleTrack{
+				Index:    stream.Index,
+				Codec:    stream.CodecName,
+				Language: stream.Tags["language"],
+				Title:    stream.Tags["title"],
+				Default:  stream.Disposition.Default != 0,
+				Forced:   stream.Disposition.Forced != 0,
+			})
 		}
 	}
 
 	if info.Width == 0 || info.Height == 0 {
-		return nil, fmt.Errorf("could not determine video dimensions from ffprobe output")
+		if info.AudioCodec == "" {
+			return nil, fmt.Errorf("could not find a video or audio stream in ffprobe output")
+		}
+		// No picture to sample; the processor renders the audio instead.
+		info.AudioOnly = true
 	}
 
 	return info, nil
 }
+
+// setVideoStream copies the properties of the selected video stream.
+func (v *VideoInfo) setVideoStream(stream ffprobeStream) {
+	v.VideoStreamIndex = stream.Index
+	v.Width = stream.Width
+	v.Height = stream.Height
+	v.VideoCodec = stream.CodecName
+	v.AvgFrameRate = stream.AvgFrameRate
+	v.SampleAspectRatio = stream.SampleAspectRatio
+	v.DisplayAspectRatio = stream.DisplayAspectRatio
+	v.Rotation = streamRotation(stream)
+	v.DisplayWidth, v.DisplayHeight = displaySize(v)
+	v.ColorSpace = stream.ColorSpace
+	v.ColorTransfer = stream.ColorTransfer
+	v.ColorPrimaries = stream.ColorPrimaries
+	v.HDRFormat = hdrFormat(stream)
+	v.FieldOrder = stream.FieldOrder
+	switch stream.FieldOrder {
+	case "tt", "bb", "tb", "bt":
+		v.Interlaced = true
+	}
+	v.VideoProfile = stream.Profile
+	v.VideoLevel = formatLevel(stream.CodecName, stream.Level)
+	v.PixelFormat = stream.PixFmt
+	v.BitDepth = bitDepth(stream)
+	v.VideoBitRate, _ = strconv.ParseInt(stream.BitRate, 10, 64)
+}
+
+// formatLevel converts ffprobe's integer codec level to the usual notation,
+// e.g. 41 to "4.1" for H.264 and 150 to "5" for HEVC.
+func formatLevel(codec string, level int) string {
+	if level <= 0 {
+		return ""
+	}
+	switch codec {
+	case "h264":
+		return strconv.FormatFloat(float64(level)/10, 'f', -1, 64)
+	case "hevc":
+		return strconv.FormatFloat(float64(level)/30, 'f', -1, 64)
+	}
+	return strconv.Itoa(level)
+}
+
+// pixFmtDepths holds the component depth of the pixel formats whose name
+// does not end in it, keyed by the name without its "le"/"be" suffix.
+// Semi-planar and packed formats name a layout (p010) or the size of a
+// whole pixel (rgb48) instead.
+var pixFmtDepths = map[string]int{
+	"p010": 10, "p012": 12, "p016": 16,
+	"p210": 10, "p212": 12, "p216": 16,
+	"p410": 10, "p412": 12, "p416": 16,
+	"nv20": 10, "y210": 10, "y212": 12, "y216": 16,
+	"v30x": 10, "xv30": 10, "xv36": 12, "xv48": 16,
+	"x2rgb10": 10, "x2bgr10": 10,
+	"rgb48": 16, "bgr48": 16, "rgba64": 16, "bgra64": 16, "ayuv64": 16,
+}
+
+// pixFmtDepthPattern matches the component depth at the end of planar, gray
+// and float pixel format names, e.g. yuv420p10, gray12, ya16 or gbrpf32.
+var pixFmtDepthPattern = regexp.MustCompile(`(?:p|gray|ya|f)(\d+)$`)
+
+// bitDepth reads the sample bit depth, falling back to the pixel format name
+// (e.g. "yuv420p10le") when ffprobe doesn't report it directly.
+func bitDepth(stream ffprobeStream) int {
+	if depth, err := strconv.Atoi(stream.BitsPerRawSample); err == nil && depth > 0 {
+		return depth
+	}
+	if stream.PixFmt == "" {
+		return 0
+	}
+	return pixFmtDepth(stream.PixFmt)
+}
+
+// pixFmtDepth returns the bits per component of an ffmpeg pixel format, 8
+// for the formats that do not state it (yuv420p, nv12, rgb24, ...).
+func pixFmtDepth(pixFmt string) int {
+	name := strings.TrimSuffix(strings.TrimSuffix(pixFmt, "le"), "be")
+	if depth, ok := pixFmtDepths[name]; ok {
+		return depth
+	}
+	if m := pixFmtDepthPattern.FindStringSubmatch(name); m != nil {
+		if depth, err := strconv.Atoi(m[1]); err == nil && depth > 0 {
+			return depth
+		}
+	}
+	return 8
+}
+
+// selectVideoStream returns the stream to take frames from. A non-negative
+// index selects that stream explicitly; otherwise attached pictures (cover
+// art) are skipped and the largest real video stream wins, preferring the
+// default one on ties. It returns nil if there is no usable video stream.
+func selectVideoStream(streams []ffprobeStream, index int) (*ffprobeStream, error) {
+	if index >= 0 {
+		for i := range streams {
+			if streams[i].Index == index {
+				if streams[i].CodecType != "video" {
+					return nil, fmt.Errorf("stream %d is a %s stream, not video", index, streams[i].CodecType)
+				}
+				return &streams[i], nil
+			}
+		}
+		return nil, fmt.Errorf("video stream %d not found", index)
+	}
+
+	var best *ffprobeStream
+	for i := range streams {
+		s := &streams[i]
+		if s.CodecType != "video" || s.Disposition.AttachedPic != 0 || s.Width == 0 || s.Height == 0 {
+			continue
+		}
+		if best == nil {
+			best = s
+			continue
+		}
+		area, bestArea := s.Width*s.Height, best.Width*best.Height
+		if area > bestArea || (area == bestArea && s.Disposition.Default != 0 && best.Disposition.Default == 0) {
+			best = s
+		}
+	}
+	return best, nil
+}
+
+// FrameSize returns the size of the frames ffmpeg decodes, which are already
+// rotated upright but still use the source's sample aspect ratio.
+func (v *VideoInfo) FrameSize() (int, int) {
+	if v.Rotation == 90 || v.Rotation == 270 {
+		return v.Height, v.Width
+	}
+	return v.Width, v.Height
+}
+
+// streamRotation reads the rotation from the display matrix side data, or
+// from the legacy "rotate" tag, normalized to clockwise degrees.
+func streamRotation(stream ffprobeStream) int {
+	var rotation float64
+	found := false
+	for _, sd := range stream.SideDataList {
+		if sd.SideDataType == "Display Matrix" {
+			// The display matrix is counter-clockwise.
+			rotation = -sd.Rotation
+			found = true
+			break
+		}
+	}
+	if !found {
+		if tag, ok := stream.Tags["rotate"]; ok {
+			if r, err := strconv.ParseFloat(tag, 64); err == nil {
+				rotation = r
+			}
+		}
+	}
+
+	degrees := int(math.Round(rotation/90)) * 90 % 360
+	if degrees < 0 {
+		degrees += 360
+	}
+	return degrees
+}
+
+// hdrFormat classifies the stream's dynamic range from its side data and
+// transfer characteristics.
+func hdrFormat(stream ffprobeStream) string {
+	for _, sd := range stream.SideDataList {
+		if sd.SideDataType == "DOVI configuration record" {
+			return "Dolby Vision"
+		}
+	}
+	switch stream.ColorTransfer {
+	case "smpte2084":
+		return "HDR10"
+	case "arib-std-b67":
+		return "HLG"
+	}
+	return ""
+}
+
+// displaySize applies the sample aspect ratio and rotation to the coded size.
+func displaySize(info *VideoInfo) (int, int) {
+	width, height := float64(info.Width), float64(info.Height)
+	if sar, ok := parseRatio(info.SampleAspectRatio); ok && sar > 0 {
+		width *= sar
+	}
+	w, h := int(math.Round(width)), int(math.Round(height))
+	if info.Rotation == 90 || info.Rotation == 270 {
+		return h, w
+	}
+	return w, h
+}
+
+// parseRatio parses ratios such as "16:9" or "30000/1001".
+func parseRatio(s string) (float64, bool) {
+	sep := strings.IndexAny(s, ":/")
+	if sep < 0 {
+		return 0, false
+	}
+	num, err := strconv.ParseFloat(s[:sep], 64)
+	if err != nil {
+		return 0, false
+	}
+	den, err := strconv.ParseFloat(s[sep+1:], 64)
+	if err != nil || den == 0 {
+		return 0, false
+	}
+	return num / den, true
+}
@@ -0,0 +1,272 @@
+package fonts
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// Info describes one installed font face.
+type Info struct {
+	Family string
+	Style  string
+	// FullName is the name the font gives itself, e.g. "Noto Sans CJK SC Bold".
+	FullName string
+	Path     string
+	// Index is the face number within a .ttc/.otc collection.
+	Index int
+}
+
+// Spec returns the font specification accepted by Load.
+func (i Info) Spec() string {
+	if i.Index == 0 {
+		return i.Path
+	}
+	return i.Path + "#" + strconv.Itoa(i.Index)
+}
+
+var (
+	discoverOnce sync.Once
+	discovered   []Info
+)
+
+// Installed returns the font faces found in the standard font directories
+// and the directories listed in the fontconfig configuration, sorted by
+// family and style. The scan runs once per process.
+func Installed() []Info {
+	discoverOnce.Do(func() {
+		seen := make(map[string]bool)
+		for _, dir := range fontDirs() {
+			filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() || seen[path] {
+					return nil
+				}
+				switch strings.ToLower(filepath.Ext(path)) {
+				case ".ttf", ".otf", ".ttc", ".otc":
+				default:
+					return nil
+				}
+				seen[path] = true
+				discovered = append(discovered, readInfo(path)...)
+				return nil
+			})
+		}
+		sort.Slice(discovered, func(a, b int) bool {
+			if discovered[a].Family != discovered[b].Family {
+				return discovered[a].Family < discovered[b].Family
+			}
+			return discovered[a].Style < discovered[b].Style
+		})
+	})
+	return discovered
+}
+
+// Find returns the specification of the installed face best matching name.
+// The name is compared, ignoring case, spaces, hyphens and underscores,
+// with the full name, "family style" and the family alone, in that order;
+// a bare family name prefers its regular style.
+func Find(name string) (string, error) {
+	want := normalizeName(name)
+	fonts := Installed()
+
+	for _, info := range fonts {
+		if normalizeName(info.FullName) == want || normalizeName(info.Family+info.Style) == want {
+			return info.Spec(), nil
+		}
+	}
+
+	var match *Info
+	for i, info := range fonts {
+		if normalizeName(info.Family) != want {
+			continue
+		}
+		if match == nil || isRegular(info.Style) && !isRegular(match.Style) {
+			match = &fonts[i]
+		}
+	}
+	if match == nil {
+		return "", fmt.Errorf("no installed font matches %q (see 'fonts list')", name)
+	}
+	return match.Spec(), nil
+}
+
+// cjkFamilies are font families covering Chinese, Japanese and Korean text,
+// in order of preference, as installed by common Linux packages, macOS and
+// Windows.
+var cjkFamilies = []string{
+	"Noto Sans CJK SC",
+	"Noto Sans SC",
+	"Source Han Sans SC",
+	"Source Han Sans",
+	"WenQuanYi Micro Hei",
+	"WenQuanYi Zen Hei",
+	"Droid Sans Fallback",
+	"PingFang SC",
+	"Hiragino Sans GB",
+	"Microsoft YaHei",
+	"SimHei",
+	"Arial Unicode MS",
+}
+
+// FindCJK returns the specification of an installed font covering CJK
+// text, the first of cjkFamilies that is installed.
+func FindCJK() (string, error) {
+	for _, family := range cjkFamilies {
+		if spec, err := Find(family); err == nil {
+			return spec, nil
+		}
+	}
+	return "", fmt.Errorf("no installed CJK font found")
+}
+
+// isFontPath reports whether a font specification names a file rather than
+// an installed font.
+func isFontPath(spec string) bool {
+	if strings.ContainsAny(spec, `/\`) {
+		return true
+	}
+	if i := strings.LastIndex(spec, "#"); i >= 0 {
+		spec = spec[:i]
+	}
+	switch strings.ToLower(filepath.Ext(spec)) {
+	case ".ttf", ".otf", ".ttc", ".otc":
+		return true
+	}
+	return false
+}
+
+func normalizeName(s string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(s))
+}
+
+func isRegular(style string) bool {
+	switch strings.ToLower(style) {
+	case "regular", "normal", "book", "roman":
+		return true
+	}
+	return false
+}
+
+// readInfo returns the faces of a font file, or nothing if it cannot be
+// parsed. Files are read lazily, as CJK collections can be very large.
+func readInfo(path string) []Info {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	coll, err := sfnt.ParseCollectionReaderAt(f)
+	if err != nil {
+		return nil
+	}
+
+	var buf sfnt.Buffer
+	var infos []Info
+	for i := 0; i < coll.NumFonts(); i++ {
+		fnt, err := coll.Font(i)
+		if err != nil {
+			continue
+		}
+		// Typographic names group faces like "Noto Sans CJK SC" + "Bold";
+		// the legacy ones split rarer weights into families of their own.
+		family := fontName(fnt, &buf, sfnt.NameIDTypographicFamily, sfnt.NameIDFamily)
+		if family == "" {
+			continue
+		}
+		infos = append(infos, Info{
+			Family:   family,
+			Style:    fontName(fnt, &buf, sfnt.NameIDTypographicSubfamily, sfnt.NameIDSubfamily),
+			FullName: fontName(fnt, &buf, sfnt.NameIDFull),
+			Path:     path,
+			Index:    i,
+		})
+	}
+	return infos
+}
+
+// fontName returns the first of the given names the font has.
+func fontName(f *sfnt.Font, buf *sfnt.Buffer, ids ...sfnt.NameID) string {
+	for _, id := range ids {
+		if name, err := f.Name(buf, id); err == nil && name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// fontDirs returns the directories to scan for fonts.
+func fontDirs() []string {
+	home, _ := os.UserHomeDir()
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" && home != "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	dirs := []string{
+		"/usr/share/fonts",
+		"/usr/local/share/fonts",
+		"/System/Library/Fonts",
+		"/Library/Fonts",
+	}
+	if dataHome != "" {
+		dirs = append(dirs, filepath.Join(dataHome, "fonts"))
+	}
+	if home != "" {
+		dirs = append(dirs, filepath.Join(home, ".fonts"), filepath.Join(home, "Library", "Fonts"))
+	}
+	if windir := os.Getenv("WINDIR"); windir != "" {
+		dirs = append(dirs, filepath.Join(windir, "Fonts"))
+	}
+
+	return append(dirs, fontconfigDirs(home, dataHome)...)
+}
+
+// fontconfigConf is the part of a fontconfig file naming font directories.
+type fontconfigConf struct {
+	Dirs []struct {
+		Prefix string `xml:"prefix,attr"`
+		Path   string `xml:",chardata"`
+	} `xml:"dir"`
+}
+
+// fontconfigDirs returns the <dir> entries of the system fontconfig
+// configuration, including the snippets in conf.d.
+func fontconfigDirs(home, dataHome string) []string {
+	files := []string{"/etc/fonts/fonts.conf", "/etc/fonts/local.conf"}
+	snippets, _ := filepath.Glob("/etc/fonts/conf.d/*.conf")
+	files = append(files, snippets...)
+
+	var dirs []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var conf fontconfigConf
+		if err := xml.Unmarshal(data, &conf); err != nil {
+			continue
+		}
+		for _, d := range conf.Dirs {
+			dir := strings.TrimSpace(d.Path)
+			switch {
+			case d.Prefix == "xdg" && dataHome != "":
+				dir = filepath.Join(dataHome, dir)
+			case strings.HasPrefix(dir, "~") && home != "":
+				dir = filepath.Join(home, dir[1:])
+			case d.Prefix == "relative":
+				dir = filepath.Join(filepath.Dir(file), dir)
+			}
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,259 @@
+// Package fonts loads the fonts used to draw text on montages. Fonts are
+// combined into a fallback chain: each character is drawn with the first
+// font that has a glyph for it, ending with a font embedded in the binary
+// so that text is always rendered. Characters none of them has, such as
+// Chinese or Japanese ones, are looked up in an installed CJK font.
+package fonts
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Chain is an ordered list of fonts, tried in turn for every character.
+type Chain struct {
+	fonts []*sfnt.Font
+	faces map[float64]font.Face
+
+	// cjk is an installed CJK font, nil if there is none. It is only
+	// searched for once a character is missing from all other fonts, as
+	// that scans the font directories.
+	cjkOnce sync.Once
+	cjk     *sfnt.Font
+}
+
+// Load builds a chain from the primary font and the fallbacks, followed by
+// the embedded default font. Each font is either the name of an installed
+// font, see Find, or a file path, optionally suffixed with "#N" to select
+// the N-th face (from 0) of a .ttc/.otc collection. An empty primary font
+// uses the embedded default on its own.
+func Load(primary string, fallbacks []string) (*Chain, error) {
+	c := &Chain{faces: make(map[float64]font.Face)}
+
+	for _, spec := range append([]string{primary}, fallbacks...) {
+		if spec == "" {
+			continue
+		}
+		if !isFontPath(spec) {
+			var err error
+			if spec, err = Find(spec); err != nil {
+				return nil, err
+			}
+		}
+		f, err := loadFile(spec)
+		if err != nil {
+			return nil, err
+		}
+		c.fonts = append(c.fonts, f)
+	}
+
+	// The embedded Go font covers Latin, Greek and Cyrillic text.
+	def, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse embedded font: %w", err)
+	}
+	c.fonts = append(c.fonts, def)
+
+	return c, nil
+}
+
+// loadFile parses a font file specification, see Load.
+func loadFile(spec string) (*sfnt.Font, error) {
+	path, index := spec, 0
+	if i := strings.LastIndex(spec, "#"); i >= 0 {
+		if n, err := strconv.Atoi(spec[i+1:]); err == nil && n >= 0 {
+			path, index = spec[:i], n
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font: %w", err)
+	}
+
+	// Collections start with the "ttcf" tag; single fonts only have face 0.
+	if !bytes.HasPrefix(data, []byte("ttcf")) {
+		if index != 0 {
+			return nil, fmt.Errorf("font %s is not a collection, cannot select face %d", path, index)
+		}
+		f, err := opentype.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse font %s: %w", path, err)
+		}
+		return f, nil
+	}
+
+	coll, err := opentype.ParseCollection(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font collection %s: %w", path, err)
+	}
+	if index >= coll.NumFonts() {
+		return nil, fmt.Errorf("font collection %s has %d faces, cannot select face %d", path, coll.NumFonts(), index)
+	}
+	f, err := coll.Font(index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse face %d of %s: %w", index, path, err)
+	}
+	return f, nil
+}
+
+// Face returns a face drawing the chain at the given size in pixels. Faces
+// are cached per size and, like all faces, must not be used concurrently.
+func (c *Chain) Face(size float64) (font.Face, error) {
+	if f, ok := c.faces[size]; ok {
+		return f, nil
+	}
+
+	f := &chainFace{
+		chain: c,
+		size:  size,
+		fonts: append([]*sfnt.Font(nil), c.fonts...),
+		pick:  make(map[rune]int),
+	}
+	for _, fnt := range c.fonts {
+		face, err := newFace(fnt, size)
+		if err != nil {
+			return nil, err
+		}
+		f.faces = append(f.faces, face)
+	}
+	c.faces[size] = f
+	return f, nil
+}
+
+// cjkFont returns the installed CJK font, searching for it the first time.
+func (c *Chain) cjkFont() *sfnt.Font {
+	c.cjkOnce.Do(func() {
+		if spec, err := FindCJK(); err == nil {
+			c.cjk, _ = loadFile(spec)
+		}
+	})
+	return c.cjk
+}
+
+func newFace(fnt *sfnt.Font, size float64) (font.Face, error) {
+	face, err := opentype.NewFace(fnt, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingNone,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create font face: %w", err)
+	}
+	return face, nil
+}
+
+// chainFace is a font.Face drawing each rune with the first font of the
+// chain that has an outline for it.
+type chainFace struct {
+	chain *Chain
+	size  float64
+	fonts []*sfnt.Font
+	faces []font.Face
+	buf   sfnt.Buffer
+	// pick caches the index of the face used for each rune.
+	pick map[rune]int
+	// triedCJK is set once the chain's CJK font was added, or found
+	// missing.
+	triedCJK bool
+}
+
+// faceFor returns the face drawing r. The first rune no font has adds the
+// installed CJK font to the end of the chain. Runes still missing fall back
+// to the primary face, which draws its "missing glyph" box.
+func (f *chainFace) faceFor(r rune) font.Face {
+	i, ok := f.pick[r]
+	if !ok {
+		i, ok = f.find(r, 0)
+		if !ok && f.addCJK() {
+			i, ok = f.find(r, len(f.fonts)-1)
+		}
+		if !ok {
+			i = 0
+		}
+		f.pick[r] = i
+	}
+	return f.faces[i]
+}
+
+// find returns the index of the first font from index from on that has an
+// outline for r.
+func (f *chainFace) find(r rune, from int) (int, bool) {
+	for j := from; j < len(f.fonts); j++ {
+		x, err := f.fonts[j].GlyphIndex(&f.buf, r)
+		if err != nil || x == 0 {
+			continue
+		}
+		// Color emoji fonts map runes to bitmaps without outlines, which
+		// cannot be drawn, so keep looking.
+		if _, err := f.fonts[j].LoadGlyph(&f.buf, x, fixed.I(16), nil); err != nil {
+			continue
+		}
+		return j, true
+	}
+	return 0, false
+}
+
+// addCJK appends the chain's CJK font on first use. It reports whether the
+// font was added by this call.
+func (f *chainFace) addCJK() bool {
+	if f.triedCJK {
+		return false
+	}
+	f.triedCJK = true
+	fnt := f.chain.cjkFont()
+	if fnt == nil {
+		return false
+	}
+	face, err := newFace(fnt, f.size)
+	if err != nil {
+		return false
+	}
+	f.fonts = append(f.fonts, fnt)
+	f.faces = append(f.faces, face)
+	return true
+}
+
+func (f *chainFace) Close() error {
+	for _, face := range f.faces {
+		face.Close()
+	}
+	return nil
+}
+
+func (f *chainFace) Glyph(dot fixed.Point26_6, r rune) (dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+	return f.faceFor(r).Glyph(dot, r)
+}
+
+func (f *chainFace) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
+	return f.faceFor(r).GlyphBounds(r)
+}
+
+func (f *chainFace) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
+	return f.faceFor(r).GlyphAdvance(r)
+}
+
+// Kern only applies between runes drawn with the same font.
+func (f *chainFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	face := f.faceFor(r0)
+	if face != f.faceFor(r1) {
+		return 0
+	}
+	return face.Kern(r0, r1)
+}
+
+// Metrics returns the metrics of the primary font.
+func (f *chainFace) Metrics() font.Metrics {
+	return f.faces[0].Metrics()
+}
@@ -0,0 +1,94 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os/exec"
+)
+
+// extractAudioFrames renders the whole audio track as one wide spectrogram
+// (or waveform) picture and cuts it into one tile per grid cell, so that
+// audio-only files get the same sheet layout as videos.
+func (p *Processor) extractAudioFrames(thumbWidth, thumbHeight int) ([]image.Image, []float64, error) {
+	numFrames := p.tileCount()
+	if numFrames <= 0 {
+		return nil, nil, fmt.Errorf("number of frames must be positive")
+	}
+
+	// Unlike video, there are no intros or credits to skip: each tile covers
+	// an equal segment of the full track and is labeled with its start time.
+	interval := p.VideoInfo.Duration / float64(numFrames)
+	timestamps := make([]float64, numFrames)
+	for i := 0; i < numFrames; i++ {
+		timestamps[i] = float64(i) * interval
+	}
+
+	var filter string
+	switch p.Config.AudioVisual {
+	case "", "spectrogram":
+		filter = fmt.Sprintf("showspectrumpic=s=%dx%d:legend=0:scale=log", numFrames*thumbWidth, thumbHeight)
+	case "waveform":
+		filter = fmt.Sprintf("showwavespic=s=%dx%d:split_channels=1:colors=white", numFrames*thumbWidth, thumbHeight)
+	default:
+		return nil, nil, fmt.Errorf("unsupported audio visualization: %s", p.Config.AudioVisual)
+	}
+
+	args := []string{
+		"-i", p.VideoInfo.Path,
+		"-lavfi", filter,
+		"-frames:v", "1",
+		"-q:v", fmt.Sprintf("%d", p.Config.JpegQuality),
+		"-f", "image2pipe",
+		"-c:v", "mjpeg",
+		"pipe:1",
+	}
+
+	cmd := exec.Command(p.Config.FfmpegPath, args...)
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, nil, fmt.Errorf("failed to execute ffmpeg: %w\nStderr: %s", err, stderr.String())
+	}
+
+	strip, _, err := image.Decode(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode audio visualization: %w", err)
+	}
+
+	sub, ok := strip.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported image type for audio visualization: %T", strip)
+	}
+
+	// Slice the strip into tiles, left to right.
+	bounds := strip.Bounds()
+	frames := make([]image.Image, numFrames)
+	for i := 0; i < numFrames; i++ {
+		x0 := bounds.Min.X + i*thumbWidth
+		frames[i] = sub.SubImage(image.Rect(x0, bounds.Min.Y, x0+thumbWidth, bounds.Min.Y+thumbHeight))
+	}
+
+	return frames, timestamps, nil
+}
+
+// formatAudioMetadataLine1 generates the first metadata line for audio-only
+// inputs: Sample rate | Channels | Bitrate
+func (p *Processor) formatAudioMetadataLine1() string {
+	rateStr := "N/A kHz"
+	if p.VideoInfo.SampleRate > 0 {
+		rateStr = fmt.Sprintf("%.1f kHz", float64(p.VideoInfo.SampleRate)/1000)
+	}
+
+	channelsStr := "N/A ch"
+	if p.VideoInfo.Channels > 0 {
+		channelsStr = fmt.Sprintf("%d ch", p.VideoInfo.Channels)
+	}
+
+	return fmt.Sprintf("%s | %s | %s", rateStr, channelsStr, p.formatBitRate())
+}
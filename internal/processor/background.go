@@ -0,0 +1,140 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/fogleman/gg"
+	"github.com/xi-mad/MontageGo/internal/csscolor"
+	"golang.org/x/image/draw"
+)
+
+// checkBackground validates the background options before any frame is
+// extracted.
+func (p *Processor) checkBackground() error {
+	switch p.Config.BackgroundGradient {
+	case "", "none", "linear", "radial":
+	default:
+		return fmt.Errorf("unsupported background gradient: %s", p.Config.BackgroundGradient)
+	}
+
+	if p.Config.BackgroundImage != "" && p.Config.BackgroundFrame {
+		return fmt.Errorf("background image and background frame cannot be used together")
+	}
+	if p.Config.BackgroundImage != "" {
+		switch p.Config.BackgroundImageFit {
+		case "", "cover", "stretch", "tile":
+		default:
+			return fmt.Errorf("unsupported background image fit: %s", p.Config.BackgroundImageFit)
+		}
+	}
+	if p.Config.BackgroundFrame {
+		if p.Config.BackgroundBlur < 0 {
+			return fmt.Errorf("background blur must not be negative")
+		}
+		if p.Config.BackgroundDim < 0 || p.Config.BackgroundDim > 1 {
+			return fmt.Errorf("background dim must be between 0 and 1")
+		}
+	}
+	return nil
+}
+
+// drawBackground fills the canvas with the background color or gradient,
+// then covers it with the background image or the blurred frame, if any.
+// The frame is taken from the middle of frames. The options have been
+// validated by checkBackground.
+func (p *Processor) drawBackground(dc *gg.Context, frames []image.Image) error {
+	width, height := dc.Width(), dc.Height()
+
+	bgColor, err := csscolor.Parse(p.Config.BackgroundColor)
+	if err != nil {
+		return fmt.Errorf("invalid --bg-color: %w", err)
+	}
+	switch p.Config.BackgroundGradient {
+	case "", "none":
+		dc.SetColor(bgColor)
+		dc.Clear()
+	case "linear", "radial":
+		toColor, err := csscolor.Parse(p.Config.BackgroundGradientTo)
+		if err != nil {
+			return fmt.Errorf("invalid --bg-gradient-to: %w", err)
+		}
+		var grad gg.Gradient
+		cx, cy := float64(width)/2, float64(height)/2
+		if p.Config.BackgroundGradient == "linear" {
+			// As in CSS, 0 degrees runs from the bottom to the top, 90 from
+			// left to right, and the gradient line reaches the corners.
+			angle := p.Config.BackgroundGradientAngle * math.Pi / 180
+			dx, dy := math.Sin(angle), -math.Cos(angle)
+			half := math.Abs(cx*dx) + math.Abs(cy*dy)
+			grad = gg.NewLinearGradient(cx-dx*half, cy-dy*half, cx+dx*half, cy+dy*half)
+		} else {
+			grad = gg.NewRadialGradient(cx, cy, 0, cx, cy, math.Hypot(cx, cy))
+		}
+		grad.AddColorStop(0, bgColor)
+		grad.AddColorStop(1, toColor)
+		dc.SetFillStyle(grad)
+		dc.DrawRectangle(0, 0, float64(width), float64(height))
+		dc.Fill()
+	}
+
+	if p.Config.BackgroundImage != "" {
+		img, err := loadOverlay(p.Config.BackgroundImage)
+		if err != nil {
+			return fmt.Errorf("failed to load background image: %w", err)
+		}
+		switch p.Config.BackgroundImageFit {
+		case "stretch":
+			scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+			draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+			dc.DrawImage(scaled, 0, 0)
+		case "tile":
+			bounds := img.Bounds()
+			for y := 0; y < height; y += bounds.Dy() {
+				for x := 0; x < width; x += bounds.Dx() {
+					dc.DrawImage(img, x, y)
+				}
+			}
+		default:
+			dc.DrawImage(coverImage(img, width, height), 0, 0)
+		}
+	}
+
+	if p.Config.BackgroundFrame && len(frames) > 0 {
+		frame := frames[len(frames)/2]
+		if frame == nil {
+			return nil
+		}
+		bg := coverImage(frame, width, height)
+		if p.Config.BackgroundBlur > 0 {
+			boxBlur(bg.Pix, width, height, bg.Stride, 4, p.Config.BackgroundBlur)
+		}
+		// Frames are opaque, so darkening scales the color channels only.
+		keep := 1 - p.Config.BackgroundDim
+		for i := 0; i < len(bg.Pix); i += 4 {
+			bg.Pix[i] = uint8(float64(bg.Pix[i]) * keep)
+			bg.Pix[i+1] = uint8(float64(bg.Pix[i+1]) * keep)
+			bg.Pix[i+2] = uint8(float64(bg.Pix[i+2]) * keep)
+		}
+		dc.DrawImage(bg, 0, 0)
+	}
+	return nil
+}
+
+// coverImage scales img to cover width x height, keeping its aspect ratio
+// and cutting off what sticks out on either side.
+func coverImage(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	scale := math.Max(float64(width)/float64(bounds.Dx()), float64(height)/float64(bounds.Dy()))
+	// The part of img that ends up on the canvas, centered.
+	srcWidth := int(math.Round(float64(width) / scale))
+	srcHeight := int(math.Round(float64(height) / scale))
+	x0 := bounds.Min.X + (bounds.Dx()-srcWidth)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-srcHeight)/2
+	src := image.Rect(x0, y0, x0+srcWidth, y0+srcHeight)
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(out, out.Bounds(), img, src, draw.Src, nil)
+	return out
+}
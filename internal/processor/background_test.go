@@ -0,0 +1,42 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xi-mad/MontageGo/internal/ffprobe"
+	"github.com/xi-mad/MontageGo/pkg/config"
+)
+
+func TestCheckBackground(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr string
+	}{
+		{"defaults", config.Config{BackgroundDim: 0.5, BackgroundBlur: 30}, ""},
+		{"image", config.Config{BackgroundImage: "bg.png", BackgroundImageFit: "tile"}, ""},
+		{"frame", config.Config{BackgroundFrame: true, BackgroundDim: 1}, ""},
+		{
+			"image and frame",
+			config.Config{BackgroundImage: "bg.png", BackgroundFrame: true},
+			"background image and background frame cannot be used together",
+		},
+		{"gradient", config.Config{BackgroundGradient: "conic"}, "unsupported background gradient: conic"},
+		{"image fit", config.Config{BackgroundImage: "bg.png", BackgroundImageFit: "fill"}, "unsupported background image fit: fill"},
+		{"blur", config.Config{BackgroundFrame: true, BackgroundBlur: -1}, "background blur must not be negative"},
+		{"dim", config.Config{BackgroundFrame: true, BackgroundDim: 1.5}, "background dim must be between 0 and 1"},
+		// The frame options do not matter without a frame background.
+		{"unused dim", config.Config{BackgroundDim: -1}, ""},
+	}
+	for _, tt := range tests {
+		p := New(&tt.cfg, &ffprobe.VideoInfo{})
+		err := p.checkBackground()
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+// barcodeSampleSize is the edge length each barcode frame is downscaled to
+// before its color is computed. Small enough to keep the pipe cheap, large
+// enough for a meaningful dominant color.
+const barcodeSampleSize = 16
+
+// checkBarcode validates the barcode options before any frame is extracted,
+// so that a typo does not cost a full ffmpeg run.
+func (p *Processor) checkBarcode() error {
+	switch p.Config.Barcode {
+	case "", "none", "header", "footer":
+	default:
+		return fmt.Errorf("unsupported barcode placement: %s", p.Config.Barcode)
+	}
+	switch p.Config.BarcodeMode {
+	case "", "average", "dominant":
+	default:
+		return fmt.Errorf("unsupported barcode mode: %s", p.Config.BarcodeMode)
+	}
+	if p.VideoInfo.AudioOnly && p.Config.BarcodeOutput != "" {
+		return fmt.Errorf("--barcode-output needs a video stream, but the input is audio only")
+	}
+	return nil
+}
+
+// sampleBarcodeColors samples about BarcodeFrames frames evenly over the
+// whole video with a single ffmpeg process and reduces each to one color.
+// Only keyframes are decoded, taking the first one after each sampling
+// interval, so that a long film is not decoded in full for a few hundred
+// stripes. Sources with keyframes further apart than the interval give
+// fewer stripes.
+func (p *Processor) sampleBarcodeColors() ([]color.RGBA, error) {
+	numFrames := p.Config.BarcodeFrames
+	if numFrames <= 0 {
+		return nil, fmt.Errorf("number of barcode frames must be positive")
+	}
+	if p.VideoInfo.Duration <= 0 {
+		return nil, fmt.Errorf("video duration is unknown, cannot sample barcode frames")
+	}
+
+	// Raw RGB is trivial to split, unlike the JPEG stream used for tiles.
+	// Black borders would darken every stripe, so honor the crop here too.
+	// The comma in gte() must be escaped for the ffmpeg filter parser.
+	interval := p.VideoInfo.Duration / float64(numFrames)
+	filters := []string{fmt.Sprintf("select='isnan(prev_selected_t)+gte(t-prev_selected_t\\,%.6f)'", interval)}
+	if p.toneMapChain != "" {
+		filters = append(filters, p.toneMapChain)
+	}
+	if p.crop != nil {
+		filters = append(filters, p.crop.filter())
+	}
+	filters = append(filters, fmt.Sprintf("scale=%d:%d:flags=area,format=rgb24", barcodeSampleSize, barcodeSampleSize))
+
+	args := append([]string{"-skip_frame", "nokey"}, p.inputArgs()...)
+	args = append(args,
+		"-vf", strings.Join(filters, ","),
+		"-frames:v", strconv.Itoa(numFrames),
+		"-fps_mode", "passthrough",
+		"-f", "rawvideo",
+		"pipe:1",
+	)
+
+	cmd := exec.Command(p.Config.FfmpegPath, args...)
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to execute ffmpeg: %w\nStderr: %s", err, stderr.String())
+	}
+
+	frameSize := barcodeSampleSize * barcodeSampleSize * 3
+	data := out.Bytes()
+	colors := make([]color.RGBA, 0, len(data)/frameSize)
+	for len(data) >= frameSize {
+		pixels := data[:frameSize]
+		data = data[frameSize:]
+
+		if p.toneMapper != nil {
+			for i := 0; i+2 < len(pixels); i += 3 {
+				pixels[i], pixels[i+1], pixels[i+2] = p.toneMapper.mapRGB(pixels[i], pixels[i+1], pixels[i+2])
+			}
+		}
+
+		// The mode was validated by checkBarcode.
+		if p.Config.BarcodeMode == "dominant" {
+			colors = append(colors, dominantColor(pixels))
+		} else {
+			colors = append(colors, averageColor(pixels))
+		}
+	}
+
+	if len(colors) == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no barcode frames. Stderr:\n%s", stderr.String())
+	}
+
+	return colors, nil
+}
+
+// averageColor returns the mean of packed RGB24 pixels.
+func averageColor(pixels []byte) color.RGBA {
+	var r, g, b int
+	n := len(pixels) / 3
+	for i := 0; i < n; i++ {
+		r += int(pixels[i*3])
+		g += int(pixels[i*3+1])
+		b += int(pixels[i*3+2])
+	}
+	return color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 255}
+}
+
+// dominantColor buckets packed RGB24 pixels into a 4-bit-per-channel
+// histogram and returns the mean color of the most populated bucket.
+func dominantColor(pixels []byte) color.RGBA {
+	type bucket struct{ r, g, b, n int }
+	var buckets [4096]bucket
+
+	best := 0
+	n := len(pixels) / 3
+	for i := 0; i < n; i++ {
+		r, g, b := int(pixels[i*3]), int(pixels[i*3+1]), int(pixels[i*3+2])
+		key := (r>>4)<<8 | (g>>4)<<4 | b>>4
+		bk := &buckets[key]
+		bk.r += r
+		bk.g += g
+		bk.b += b
+		bk.n++
+		if bk.n > buckets[best].n {
+			best = key
+		}
+	}
+
+	bk := buckets[best]
+	return color.RGBA{R: uint8(bk.r / bk.n), G: uint8(bk.g / bk.n), B: uint8(bk.b / bk.n), A: 255}
+}
+
+// drawBarcode paints the sampled colors as vertical stripes filling the
+// given rectangle.
+func drawBarcode(dc *gg.Context, colors []color.RGBA, x, y, width, height int) {
+	for i, c := range colors {
+		x0 := x + i*width/len(colors)
+		x1 := x + (i+1)*width/len(colors)
+		if x1 == x0 {
+			continue
+		}
+		dc.SetColor(c)
+		dc.DrawRectangle(float64(x0), float64(y), float64(x1-x0), float64(height))
+		dc.Fill()
+	}
+}
+
+// saveBarcode writes the barcode as a standalone image. The format follows
+// the file extension (.png, otherwise JPEG).
+func (p *Processor) saveBarcode(colors []color.RGBA, width int) error {
+	// A standalone barcode reads better taller than the band in the sheet.
+	height := width / 4
+	if height < p.Config.BarcodeHeight {
+		height = p.Config.BarcodeHeight
+	}
+
+	dc := gg.NewContext(width, height)
+	drawBarcode(dc, colors, 0, 0, width, height)
+
+	return p.saveImage(dc.Image(), p.Config.BarcodeOutput, imageFormat(p.Config.BarcodeOutput))
+}
@@ -0,0 +1,195 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fogleman/gg"
+	"github.com/xi-mad/MontageGo/internal/csscolor"
+)
+
+// captionBoxOpacity is the opacity of the strip behind captions drawn over
+// the tile.
+const captionBoxOpacity = 0.6
+
+var (
+	// srtTiming matches the timing line of an SRT cue.
+	srtTiming = regexp.MustCompile(`(\d+):(\d{2}):(\d{2})[,.](\d{3})\s*-->\s*(\d+):(\d{2}):(\d{2})[,.](\d{3})`)
+	// subtitleMarkup matches HTML-like tags and ASS override blocks.
+	subtitleMarkup = regexp.MustCompile(`<[^>]*>|\{[^}]*\}`)
+)
+
+// subtitleCue is one subtitle line and the time span it is shown for.
+type subtitleCue struct {
+	start, end float64
+	text       string
+}
+
+// captionStyle holds the resolved caption settings for one montage.
+type captionStyle struct {
+	position   string
+	fontSize   float64
+	maxLines   int
+	lineHeight float64
+	color      color.Color
+	shadow     color.Color
+}
+
+// loadCaptions reads the subtitle source named by --captions: "auto" for
+// the default text subtitle track, a stream index, or the path of an
+// SRT/ASS/VTT file. It returns nil if captions are disabled.
+func (p *Processor) loadCaptions() ([]subtitleCue, error) {
+	source := p.Config.Captions
+	if source == "" || source == "none" {
+		return nil, nil
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error"}
+	if track, isStream, err := p.subtitleTrack(source); err != nil {
+		return nil, err
+	} else if isStream {
+		args = append(args, "-i", p.VideoInfo.Path, "-map", fmt.Sprintf("0:%d", track.Index))
+	} else {
+		if _, err := os.Stat(source); err != nil {
+			return nil, fmt.Errorf("subtitle file not found: %w", err)
+		}
+		args = append(args, "-i", source)
+	}
+	// Letting ffmpeg convert everything to SRT keeps a single parser.
+	args = append(args, "-f", "srt", "pipe:1")
+
+	cmd := exec.Command(p.Config.FfmpegPath, args...)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to read subtitles: %w\nStderr: %s", err, stderr.String())
+	}
+	return parseSRT(out.String()), nil
+}
+
+// parseSRT parses SRT cues, dropping formatting tags. Malformed cues are
+// skipped.
+func parseSRT(data string) []subtitleCue {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	var cues []subtitleCue
+	for _, block := range strings.Split(data, "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		for i, line := range lines {
+			m := srtTiming.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			text := subtitleMarkup.ReplaceAllString(strings.Join(lines[i+1:], " "), "")
+			text = strings.Join(strings.Fields(text), " ")
+			if text != "" {
+				cues = append(cues, subtitleCue{
+					start: srtSeconds(m[1:5]),
+					end:   srtSeconds(m[5:9]),
+					text:  text,
+				})
+			}
+			break
+		}
+	}
+	return cues
+}
+
+// srtSeconds converts hours, minutes, seconds and milliseconds to seconds.
+func srtSeconds(parts []string) float64 {
+	var v [4]float64
+	for i, s := range parts {
+		v[i], _ = strconv.ParseFloat(s, 64)
+	}
+	return v[0]*3600 + v[1]*60 + v[2] + v[3]/1000
+}
+
+// captionAt returns the text of the cues on screen at the given time, or an
+// empty string if nothing is shown.
+func captionAt(cues []subtitleCue, seconds float64) string {
+	var texts []string
+	for _, cue := range cues {
+		if seconds >= cue.start && seconds < cue.end {
+			texts = append(texts, cue.text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// resolveCaptionStyle validates the caption options. It returns nil if
+// captions are disabled.
+func (p *Processor) resolveCaptionStyle() (*captionStyle, error) {
+	if p.captions == nil {
+		return nil, nil
+	}
+
+	style := &captionStyle{
+		position: p.Config.CaptionPosition,
+		fontSize: float64(p.Config.CaptionSize),
+		maxLines: p.Config.CaptionLines,
+	}
+	switch style.position {
+	case "":
+		style.position = "below"
+	case "below", "over":
+	default:
+		return nil, fmt.Errorf("unsupported caption position: %s", style.position)
+	}
+	if style.fontSize <= 0 {
+		style.fontSize = 16
+	}
+	if style.maxLines <= 0 {
+		style.maxLines = 2
+	}
+	style.lineHeight = math.Ceil(style.fontSize * 1.3)
+
+	var err error
+	if style.color, err = csscolor.Parse(p.Config.FontColor); err != nil {
+		return nil, fmt.Errorf("invalid --font-color: %w", err)
+	}
+	if style.shadow, err = csscolor.Parse(p.Config.ShadowColor); err != nil {
+		return nil, fmt.Errorf("invalid --shadow-color: %w", err)
+	}
+	return style, nil
+}
+
+// belowHeight returns the height of the caption area reserved under each
+// tile. Room for every line is kept so that all rows line up.
+func (s *captionStyle) belowHeight() int {
+	if s == nil || s.position != "below" {
+		return 0
+	}
+	return int(float64(s.maxLines)*s.lineHeight) + 2*timestampBoxPadding
+}
+
+// draw draws a caption wrapped to the tile width, either in the area at
+// top (below the tile) or over the bottom of the tile. The caption font
+// must already be set.
+func (s *captionStyle) draw(dc *gg.Context, text string, x, top, thumbWidth int) {
+	lines := layoutText(dc, text, float64(thumbWidth-2*timestampBoxPadding), s.maxLines)
+
+	blockH := float64(len(lines))*s.lineHeight + 2*timestampBoxPadding
+	y := float64(top)
+	if s.position == "over" {
+		y -= blockH
+		dc.SetColor(color.NRGBA{A: uint8(captionBoxOpacity * 255)})
+		dc.DrawRectangle(float64(x), y, float64(thumbWidth), blockH)
+		dc.Fill()
+	}
+
+	centerX := float64(x) + float64(thumbWidth)/2
+	for i, line := range lines {
+		lineY := y + timestampBoxPadding + float64(i)*s.lineHeight
+		dc.SetColor(s.shadow)
+		dc.DrawStringAnchored(line.text, centerX+1, lineY+1, 0.5, 1)
+		dc.SetColor(s.color)
+		dc.DrawStringAnchored(line.text, centerX, lineY, 0.5, 1)
+	}
+}
@@ -0,0 +1,91 @@
+package processor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSRT(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []subtitleCue
+	}{
+		{
+			name: "basic",
+			data: "1\n00:00:01,500 --> 00:00:03,000\nHello\n\n2\n00:00:04,000 --> 00:00:05,250\nWorld\n",
+			want: []subtitleCue{
+				{start: 1.5, end: 3, text: "Hello"},
+				{start: 4, end: 5.25, text: "World"},
+			},
+		},
+		{
+			name: "multi-line text is joined",
+			data: "1\n00:00:01,000 --> 00:00:02,000\nfirst line\nsecond line\n",
+			want: []subtitleCue{{start: 1, end: 2, text: "first line second line"}},
+		},
+		{
+			name: "CRLF line endings",
+			data: "1\r\n00:00:01,000 --> 00:00:02,000\r\nHello\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nAgain\r\n",
+			want: []subtitleCue{
+				{start: 1, end: 2, text: "Hello"},
+				{start: 3, end: 4, text: "Again"},
+			},
+		},
+		{
+			name: "markup is dropped",
+			data: "1\n00:00:01,000 --> 00:00:02,000\n<i>Hello</i> {\\an8}<font color=\"red\">there</font>\n",
+			want: []subtitleCue{{start: 1, end: 2, text: "Hello there"}},
+		},
+		{
+			name: "period separator and hours",
+			data: "1\n01:01:01.250 --> 01:01:02.000\nLate\n",
+			want: []subtitleCue{{start: 3661.25, end: 3662, text: "Late"}},
+		},
+		{
+			name: "missing index line",
+			data: "00:00:01,000 --> 00:00:02,000\nNo index\n",
+			want: []subtitleCue{{start: 1, end: 2, text: "No index"}},
+		},
+		{
+			name: "malformed and empty cues are skipped",
+			data: "1\n00:00:01 --> 00:00:02\nBad timing\n\n2\n00:00:03,000 --> 00:00:04,000\n<i></i>\n\n3\n00:00:05,000 --> 00:00:06,000\nGood\n",
+			want: []subtitleCue{{start: 5, end: 6, text: "Good"}},
+		},
+		{
+			name: "empty input",
+			data: "",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		if got := parseSRT(tt.data); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseSRT() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCaptionAt(t *testing.T) {
+	cues := []subtitleCue{
+		{start: 1, end: 3, text: "one"},
+		{start: 2, end: 4, text: "two"},
+		{start: 5, end: 6, text: "three"},
+	}
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0.5, ""},
+		{1, "one"},
+		{2.5, "one two"},
+		// A cue is no longer shown at its end time.
+		{3, "two"},
+		{4.5, ""},
+		{5.5, "three"},
+	}
+	for _, tt := range tests {
+		if got := captionAt(cues, tt.seconds); got != tt.want {
+			t.Errorf("captionAt(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package processor
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+// chapterLabelOpacity is the opacity of the strip behind chapter labels.
+const chapterLabelOpacity = 0.6
+
+// tileTimestamps returns the times, in seconds, of the frames shown in the
+// grid, in ascending order.
+func (p *Processor) tileTimestamps() ([]float64, error) {
+	switch p.Config.Select {
+	case "", "uniform":
+	case "chapters":
+		// Files without chapters get a regular sheet rather than an error,
+		// so that whole folders can be processed with --select chapters.
+		if len(p.VideoInfo.Chapters) > 0 {
+			return p.chapterTimestamps()
+		}
+	default:
+		return nil, fmt.Errorf("unsupported frame selection: %s", p.Config.Select)
+	}
+
+	numFrames := p.tileCount()
+	if numFrames <= 0 {
+		return nil, fmt.Errorf("number of frames must be positive")
+	}
+
+	// Use 90% of the video duration, skipping the first and last 5%.
+	duration := p.VideoInfo.Duration * 0.9
+	startOffset := p.VideoInfo.Duration * 0.05
+	interval := duration / float64(numFrames)
+
+	timestamps := make([]float64, numFrames)
+	for i := 0; i < numFrames; i++ {
+		timestamps[i] = startOffset + (float64(i) * interval)
+	}
+	return timestamps, nil
+}
+
+// chapterTimestamps takes --frames-per-chapter frames from every chapter,
+// each from the middle of an equal slice of the chapter so that the title
+// cards at chapter boundaries are avoided.
+func (p *Processor) chapterTimestamps() ([]float64, error) {
+	perChapter := p.Config.FramesPerChapter
+	if perChapter <= 0 {
+		return nil, fmt.Errorf("frames per chapter must be positive")
+	}
+
+	var timestamps []float64
+	for _, ch := range p.VideoInfo.Chapters {
+		end := ch.End
+		if p.VideoInfo.Duration > 0 && end > p.VideoInfo.Duration {
+			end = p.VideoInfo.Duration
+		}
+		if end <= ch.Start {
+			continue
+		}
+		slice := (end - ch.Start) / float64(perChapter)
+		for i := 0; i < perChapter; i++ {
+			timestamps = append(timestamps, ch.Start+(float64(i)+0.5)*slice)
+		}
+	}
+	if len(timestamps) == 0 {
+		return nil, fmt.Errorf("no chapter lies within the video")
+	}
+	return timestamps, nil
+}
+
+// chapterAt returns the index of the chapter playing at the given time, or
+// -1 if there is none.
+func (p *Processor) chapterAt(seconds float64) int {
+	for i, ch := range p.VideoInfo.Chapters {
+		if seconds >= ch.Start && seconds < ch.End {
+			return i
+		}
+	}
+	return -1
+}
+
+// chapterTitle returns the title of the chapter, numbering untitled ones.
+func (p *Processor) chapterTitle(index int) string {
+	if title := strings.TrimSpace(p.VideoInfo.Chapters[index].Title); title != "" {
+		return title
+	}
+	return fmt.Sprintf("Chapter %d", index+1)
+}
+
+// chapterLabels returns the label of each tile: the chapter title on the
+// first tile of every chapter and an empty string elsewhere.
+func (p *Processor) chapterLabels(timestamps []float64) []string {
+	labels := make([]string, len(timestamps))
+	previous := -1
+	for i, ts := range timestamps {
+		chapter := p.chapterAt(ts)
+		if chapter >= 0 && chapter != previous {
+			labels[i] = p.chapterTitle(chapter)
+		}
+		previous = chapter
+	}
+	return labels
+}
+
+// drawChapterLabel draws a chapter title in a strip across the top of the
+// tile, or across the bottom if the timestamp already sits at the top. The
+// font must already be loaded.
+func drawChapterLabel(dc *gg.Context, label string, atBottom bool, textColor, shadowColor color.Color, x, y, thumbWidth, thumbHeight int) {
+	line := fitText(dc, label, float64(thumbWidth-2*timestampBoxPadding))
+
+	w, h := dc.MeasureString(line.text)
+	stripH := h + 2*timestampBoxPadding
+	stripY := float64(y)
+	if atBottom {
+		stripY = float64(y+thumbHeight) - stripH
+	}
+
+	dc.SetColor(color.NRGBA{A: uint8(chapterLabelOpacity * 255)})
+	dc.DrawRectangle(float64(x), stripY, float64(thumbWidth), stripH)
+	dc.Fill()
+
+	// Right-to-left titles start at the right edge.
+	textX := float64(x + timestampBoxPadding)
+	if line.rtl {
+		textX = float64(x+thumbWidth-timestampBoxPadding) - w
+	}
+	textY := stripY + timestampBoxPadding
+	dc.SetColor(shadowColor)
+	dc.DrawStringAnchored(line.text, textX+1, textY+1, 0, 1)
+	dc.SetColor(textColor)
+	dc.DrawStringAnchored(line.text, textX, textY, 0, 1)
+}
@@ -0,0 +1,186 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+const (
+	// cropSamplePoints is how many positions of the video are probed with
+	// cropdetect when --crop auto is used.
+	cropSamplePoints = 7
+	// cropSampleFrames is how many consecutive frames cropdetect looks at
+	// for each sample point.
+	cropSampleFrames = 10
+)
+
+var cropDetectPattern = regexp.MustCompile(`crop=(\d+):(\d+):(\d+):(\d+)`)
+
+// cropSpecPattern matches a whole manual crop specification, WxH:X:Y.
+var cropSpecPattern = regexp.MustCompile(`^(\d+)x(\d+):(\d+):(\d+)$`)
+
+// cropRect is a crop area in source pixels, as used by ffmpeg's crop filter.
+type cropRect struct {
+	W, H, X, Y int
+}
+
+// String formats the rectangle the way --crop accepts it.
+func (c cropRect) String() string {
+	return fmt.Sprintf("%dx%d:%d:%d", c.W, c.H, c.X, c.Y)
+}
+
+// filter returns the ffmpeg crop filter for the rectangle.
+func (c cropRect) filter() string {
+	return fmt.Sprintf("crop=%d:%d:%d:%d", c.W, c.H, c.X, c.Y)
+}
+
+// parseCrop parses a manual crop specification of the form WxH:X:Y.
+func parseCrop(s string) (*cropRect, error) {
+	m := cropSpecPattern.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("invalid crop %q, expected auto, none or WxH:X:Y", s)
+	}
+	var c cropRect
+	for i, v := range []*int{&c.W, &c.H, &c.X, &c.Y} {
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid crop %q, expected auto, none or WxH:X:Y", s)
+		}
+		*v = n
+	}
+	if c.W <= 0 || c.H <= 0 {
+		return nil, fmt.Errorf("invalid crop %q, size must be positive", s)
+	}
+	return &c, nil
+}
+
+// resolveCrop turns the --crop setting into a crop area, running black border
+// detection for "auto". A nil result means the frames are used uncropped.
+func (p *Processor) resolveCrop() (*cropRect, error) {
+	switch p.Config.Crop {
+	case "", "none":
+		return nil, nil
+	case "auto":
+		return p.detectCrop()
+	default:
+		c, err := parseCrop(p.Config.Crop)
+		if err != nil {
+			return nil, err
+		}
+		// ffmpeg would only fail once extraction starts, with the reason
+		// buried in its log.
+		frameWidth, frameHeight := p.VideoInfo.FrameSize()
+		if frameWidth > 0 && (c.X+c.W > frameWidth || c.Y+c.H > frameHeight) {
+			return nil, fmt.Errorf("crop %s does not fit in the %dx%d frame", c, frameWidth, frameHeight)
+		}
+		return c, nil
+	}
+}
+
+// detectCrop runs ffmpeg's cropdetect at several points of the video in
+// parallel and returns the black border crop that is stable across them.
+// It returns nil if there are no borders to remove.
+func (p *Processor) detectCrop() (*cropRect, error) {
+	duration := p.VideoInfo.Duration * 0.9
+	startOffset := p.VideoInfo.Duration * 0.05
+
+	results := make([]*cropRect, cropSamplePoints)
+	errs := make(chan error, cropSamplePoints)
+	var wg sync.WaitGroup
+
+	for i := 0; i < cropSamplePoints; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			ts := startOffset + duration*(float64(index)+0.5)/cropSamplePoints
+			args := []string{"-ss", fmt.Sprintf("%.4f", ts)}
+			args = append(args, p.inputArgs()...)
+			args = append(args,
+				"-vf", "cropdetect=limit=24:round=2:reset=0",
+				"-frames:v", fmt.Sprintf("%d", cropSampleFrames),
+				"-f", "null",
+				"-",
+			)
+
+			cmd := exec.Command(p.Config.FfmpegPath, args...)
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+			if err := cmd.Run(); err != nil {
+				errs <- fmt.Errorf("failed to execute ffmpeg cropdetect: %w\nStderr: %s", err, stderr.String())
+				return
+			}
+
+			// cropdetect logs one line per frame; with reset=0 the last one
+			// covers all frames seen at this sample point.
+			matches := cropDetectPattern.FindAllStringSubmatch(stderr.String(), -1)
+			if len(matches) == 0 {
+				return
+			}
+			m := matches[len(matches)-1]
+			var c cropRect
+			c.W, _ = strconv.Atoi(m[1])
+			c.H, _ = strconv.Atoi(m[2])
+			c.X, _ = strconv.Atoi(m[3])
+			c.Y, _ = strconv.Atoi(m[4])
+			if c.W > 0 && c.H > 0 {
+				results[index] = &c
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		return nil, err
+	}
+
+	crop := stableCrop(results)
+	frameWidth, frameHeight := p.VideoInfo.FrameSize()
+	if crop == nil || (crop.W == frameWidth && crop.H == frameHeight) {
+		return nil, nil
+	}
+	return crop, nil
+}
+
+// stableCrop picks the crop detected most often. If no two sample points
+// agree, it falls back to the union of all detected areas so that a dark
+// scene can never cut into picture content.
+func stableCrop(results []*cropRect) *cropRect {
+	counts := make(map[cropRect]int)
+	var union *cropRect
+	for _, c := range results {
+		if c == nil {
+			continue
+		}
+		counts[*c]++
+		if union == nil {
+			u := *c
+			union = &u
+			continue
+		}
+		x1 := max(union.X+union.W, c.X+c.W)
+		y1 := max(union.Y+union.H, c.Y+c.H)
+		union.X = min(union.X, c.X)
+		union.Y = min(union.Y, c.Y)
+		union.W = x1 - union.X
+		union.H = y1 - union.Y
+	}
+
+	var best cropRect
+	bestCount := 0
+	for c, n := range counts {
+		if n > bestCount || (n == bestCount && c.W*c.H > best.W*best.H) {
+			best = c
+			bestCount = n
+		}
+	}
+	if bestCount >= 2 {
+		return &best
+	}
+	return union
+}
@@ -0,0 +1,29 @@
+package processor
+
+import "fmt"
+
+// resolveDeinterlace picks the deinterlace filter according to --deinterlace
+// and the field order reported by ffprobe.
+func (p *Processor) resolveDeinterlace() error {
+	switch p.Config.Deinterlace {
+	case "", "auto":
+		if !p.VideoInfo.Interlaced {
+			return nil
+		}
+	case "on":
+	case "off":
+		return nil
+	default:
+		return fmt.Errorf("unsupported deinterlace mode: %s", p.Config.Deinterlace)
+	}
+
+	// bwdif gives cleaner motion than yadif but is missing from older builds.
+	// send_frame keeps one output frame per input frame, so frame numbers
+	// used by select stay valid.
+	if p.hasFilter("bwdif") {
+		p.deinterlaceFilter = "bwdif=mode=send_frame:deint=all"
+	} else {
+		p.deinterlaceFilter = "yadif=mode=send_frame:deint=all"
+	}
+	return nil
+}
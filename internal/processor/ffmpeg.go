@@ -0,0 +1,39 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// inputArgs returns the ffmpeg arguments opening the input and selecting the
+// video stream frames are taken from.
+func (p *Processor) inputArgs() []string {
+	return []string{
+		"-i", p.VideoInfo.Path,
+		"-map", fmt.Sprintf("0:%d", p.VideoInfo.VideoStreamIndex),
+	}
+}
+
+// hasFilter reports whether the configured ffmpeg build provides the named
+// filter. The filter list is queried once per processor.
+func (p *Processor) hasFilter(name string) bool {
+	if p.filters == nil {
+		p.filters = make(map[string]bool)
+
+		cmd := exec.Command(p.Config.FfmpegPath, "-hide_banner", "-filters")
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		if err := cmd.Run(); err == nil {
+			// Lines look like " ... zscale            V->V       Apply resizing, ..."
+			for _, line := range strings.Split(out.String(), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) >= 3 {
+					p.filters[fields[1]] = true
+				}
+			}
+		}
+	}
+	return p.filters[name]
+}
@@ -0,0 +1,150 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// maxAutoTiles caps the number of tiles --interval and --count may ask
+	// for, as every tile is a frame ffmpeg has to decode.
+	maxAutoTiles = 400
+	// minAutoThumbWidth is the narrowest tile --canvas-width may produce.
+	minAutoThumbWidth = 120
+	// autoGridAspect is the width-to-height ratio the automatic layout aims
+	// the grid at, that of a landscape sheet.
+	autoGridAspect = 4.0 / 3
+)
+
+// tileCount returns the number of tiles in a uniform sheet: the one set by
+// --count or --interval, which may leave the last row short, or else a full
+// grid.
+func (p *Processor) tileCount() int {
+	if p.Config.TileCount > 0 {
+		return p.Config.TileCount
+	}
+	return p.Config.Columns * p.Config.Rows
+}
+
+// resolveLayout applies the automatic layout options to the grid settings.
+// --interval or --count set the number of tiles, and the columns and rows
+// are chosen for a sheet of pleasant proportions; --canvas-width sets the
+// tile width so that the sheet is that wide. It must run after the crop is
+// known, as the grid follows the aspect ratio of the tiles.
+func (p *Processor) resolveLayout() error {
+	cfg := p.Config
+	if cfg.CanvasWidth < 0 || cfg.Interval < 0 || cfg.TileCount < 0 {
+		return fmt.Errorf("canvas width, interval and tile count must not be negative")
+	}
+	if cfg.Interval > 0 && cfg.TileCount > 0 {
+		return fmt.Errorf("interval and count cannot be used together")
+	}
+
+	// Audio tiles are widescreen, as in Run.
+	aspect := 16.0 / 9
+	if !p.VideoInfo.AudioOnly {
+		aspect = p.displayAspect()
+	}
+	// thumbSize returns the tile size for the given number of columns.
+	thumbSize := func(columns int) (int, int) {
+		width := cfg.ThumbWidth
+		if cfg.CanvasWidth > 0 {
+			width = (cfg.CanvasWidth - 2*cfg.Margin - (columns-1)*cfg.Padding) / columns
+		}
+		if cfg.ThumbHeight > 0 {
+			return width, cfg.ThumbHeight
+		}
+		if aspect == 0 {
+			return width, width
+		}
+		return width, int(float64(width) / aspect)
+	}
+
+	tiles, err := p.autoTiles()
+	if err != nil {
+		return err
+	}
+	if tiles == 0 {
+		// Only the width is given: keep the grid and fit the tiles to it.
+		if cfg.CanvasWidth == 0 {
+			return nil
+		}
+		if cfg.Columns <= 0 {
+			return fmt.Errorf("number of columns must be positive")
+		}
+		width, _ := thumbSize(cfg.Columns)
+		if width < minAutoThumbWidth {
+			return fmt.Errorf("--canvas-width %d is too narrow for %d columns", cfg.CanvasWidth, cfg.Columns)
+		}
+		cfg.ThumbWidth = width
+		return nil
+	}
+
+	// Try every column count, scoring how far the grid is from the target
+	// proportions and how empty its last row is.
+	bestColumns, bestRows := 0, 0
+	bestScore := math.Inf(1)
+	for columns := 1; columns <= tiles; columns++ {
+		width, height := thumbSize(columns)
+		// More columns only make the tiles narrower.
+		if cfg.CanvasWidth > 0 && width < minAutoThumbWidth {
+			break
+		}
+
+		rows := (tiles + columns - 1) / columns
+		penalty := float64(rows*columns-tiles) / float64(columns)
+
+		gridWidth := columns*width + (columns-1)*cfg.Padding
+		gridHeight := rows*height + (rows-1)*cfg.Padding
+		score := math.Abs(math.Log(float64(gridWidth)/float64(gridHeight)/autoGridAspect)) + penalty
+		if score < bestScore {
+			bestColumns, bestRows, bestScore = columns, rows, score
+		}
+	}
+	if bestColumns == 0 {
+		return fmt.Errorf("--canvas-width %d is too narrow for a single column", cfg.CanvasWidth)
+	}
+
+	cfg.Columns, cfg.Rows = bestColumns, bestRows
+	if cfg.CanvasWidth > 0 {
+		cfg.ThumbWidth, _ = thumbSize(bestColumns)
+	}
+	return nil
+}
+
+// autoTiles returns how many tiles the automatic layout arranges, 0 when
+// the grid comes from --columns and --rows. A count worked out from
+// --interval is stored in TileCount, so that exactly that many tiles are
+// taken.
+func (p *Processor) autoTiles() (int, error) {
+	cfg := p.Config
+	if cfg.Interval == 0 && cfg.TileCount == 0 {
+		return 0, nil
+	}
+
+	// Chapter selection decides the frame count itself.
+	if !p.VideoInfo.AudioOnly && cfg.Select == "chapters" && len(p.VideoInfo.Chapters) > 0 {
+		return len(p.VideoInfo.Chapters) * max(cfg.FramesPerChapter, 1), nil
+	}
+	if cfg.TileCount > 0 {
+		if cfg.TileCount > maxAutoTiles {
+			return 0, fmt.Errorf("--count %d is more than the %d tiles allowed", cfg.TileCount, maxAutoTiles)
+		}
+		return cfg.TileCount, nil
+	}
+
+	if p.VideoInfo.Duration <= 0 {
+		return 0, fmt.Errorf("duration is unknown, cannot use --interval")
+	}
+	// Video sheets skip the first and last 5%, see tileTimestamps.
+	span := p.VideoInfo.Duration
+	if !p.VideoInfo.AudioOnly {
+		span *= 0.9
+	}
+	tiles := max(int(math.Round(span/cfg.Interval.Seconds())), 1)
+	if tiles > maxAutoTiles {
+		return 0, fmt.Errorf("--interval %s gives %d tiles, more than the %d allowed; use a longer interval", cfg.Interval, tiles, maxAutoTiles)
+	}
+	cfg.TileCount = tiles
+	return tiles, nil
+}
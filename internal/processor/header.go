@@ -0,0 +1,404 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/fogleman/gg"
+	"github.com/xi-mad/MontageGo/internal/csscolor"
+	"golang.org/x/image/draw"
+)
+
+const (
+	// metadataLineSpacing is the vertical distance between metadata lines.
+	metadataLineSpacing = 25
+	// minTitleSize is the smallest font size long titles shrink to before
+	// they are wrapped.
+	minTitleSize = 20
+	// headerPadding is the space above and below the header text.
+	headerPadding = 6
+	// titleGap is the space between the title and the lines below it.
+	titleGap = 14
+	// titleSize is the font size of the title before it is shrunk to fit.
+	titleSize = 40
+	// metadataSize is the font size of the metadata lines.
+	metadataSize = 20
+	// tableSize and tableRowHeight size the key/value table.
+	tableSize      = 18
+	tableRowHeight = 24
+	// tableKeyOpacity dims the keys of the key/value table against the
+	// values.
+	tableKeyOpacity = 0.65
+)
+
+// headerText is one line of header text, placed relative to the header.
+type headerText struct {
+	line textLine
+	size float64
+	// x, y is the anchor point: the vertical center of the line and its
+	// left edge, center or right edge depending on ax (0, 0.5 or 1).
+	x, y, ax float64
+	// bottom is the lowest point of the line, used to drop lines that do
+	// not fit a fixed header height.
+	bottom float64
+	color  color.Color
+	// shadowOffset is how far the shadow is offset down and to the right.
+	shadowOffset float64
+}
+
+// header is the header laid out for the width of the montage.
+type header struct {
+	height int
+	texts  []headerText
+	shadow color.Color
+	// poster is the frame shown next to the text in the "left" style,
+	// already scaled, with its position within the header.
+	poster           image.Image
+	posterX, posterY int
+	// logo is the scaled --logo image and its position within the header.
+	logo         image.Image
+	logoX, logoY int
+}
+
+// layoutHeader arranges the header text in the configured style. With
+// --header -1 the header grows to fit its content; with a fixed height,
+// lines that do not fit are left out. poster is a frame for the "left"
+// style, or nil. The logo, if any, sits at one end and the text uses the
+// space left over.
+func (p *Processor) layoutHeader(width int, poster image.Image) (*header, error) {
+	if p.Config.HeaderHeight == 0 {
+		return &header{}, nil
+	}
+
+	h := &header{}
+	var err error
+	if h.shadow, err = csscolor.Parse(p.Config.ShadowColor); err != nil {
+		return nil, fmt.Errorf("invalid --shadow-color: %w", err)
+	}
+	fontColor, err := csscolor.Parse(p.Config.FontColor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --font-color: %w", err)
+	}
+
+	lines, err := p.headerLines()
+	if err != nil {
+		return nil, err
+	}
+
+	left, right := float64(p.Config.Margin), float64(width-p.Config.Margin)
+	if p.logo != nil {
+		if err := p.layoutLogo(h, width); err != nil {
+			return nil, err
+		}
+		if h.logo != nil {
+			if space := float64(h.logo.Bounds().Dx() + logoGap); h.logoX < width/2 {
+				left += space
+			} else {
+				right -= space
+			}
+		}
+	}
+
+	// Text is measured on a scratch context and drawn later.
+	dc := gg.NewContext(1, 1)
+
+	var contentHeight float64
+	switch p.Config.HeaderStyle {
+	case "", "centered":
+		contentHeight, err = p.layoutCenteredHeader(h, dc, lines, width, left, right, fontColor)
+	case "left":
+		contentHeight, err = p.layoutLeftHeader(h, dc, lines, left, right, fontColor, poster)
+	case "table":
+		contentHeight, err = p.layoutTableHeader(h, dc, lines, left, right, fontColor)
+	default:
+		return nil, fmt.Errorf("unsupported header style: %s", p.Config.HeaderStyle)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if h.logo != nil {
+		contentHeight = math.Max(contentHeight, float64(headerPadding+h.logo.Bounds().Dy()))
+	}
+
+	if p.Config.HeaderHeight < 0 {
+		h.height = int(math.Ceil(contentHeight)) + 2*headerPadding
+	} else {
+		h.height = p.Config.HeaderHeight
+	}
+	if h.logo != nil {
+		h.logoY = (h.height - h.logo.Bounds().Dy()) / 2
+	}
+	if p.Config.HeaderHeight < 0 {
+		return h, nil
+	}
+
+	fitting := h.texts[:0]
+	for _, t := range h.texts {
+		if t.bottom <= float64(h.height) {
+			fitting = append(fitting, t)
+		}
+	}
+	h.texts = fitting
+	return h, nil
+}
+
+// layoutTitle places the title at the top of the header, shrinking it to
+// fit maxWidth and wrapping it onto two lines if it still does not fit. x
+// and ax anchor the lines as in headerText. It returns where the title
+// ends.
+func (p *Processor) layoutTitle(h *header, dc *gg.Context, title string, x, ax, maxWidth float64, fontColor color.NRGBA) (float64, error) {
+	fontSize := float64(titleSize)
+	for {
+		if err := p.setFont(dc, fontSize); err != nil {
+			return 0, err
+		}
+		w, _ := dc.MeasureString(shapeArabic(title))
+		if w < maxWidth || fontSize <= minTitleSize {
+			break
+		}
+		fontSize -= 2
+	}
+
+	y := float64(headerPadding)
+	lineHeight := fontSize * textLineFactor
+	for _, line := range layoutText(dc, title, maxWidth, 2) {
+		h.texts = append(h.texts, headerText{
+			line:         line,
+			size:         fontSize,
+			x:            x,
+			y:            y + lineHeight/2,
+			ax:           ax,
+			bottom:       y + lineHeight,
+			color:        fontColor,
+			shadowOffset: 2,
+		})
+		y += lineHeight
+	}
+	return y, nil
+}
+
+// layoutCenteredHeader centers the title and the metadata lines on the
+// canvas, keeping them between left and right.
+func (p *Processor) layoutCenteredHeader(h *header, dc *gg.Context, lines []string, width int, left, right float64, fontColor color.NRGBA) (float64, error) {
+	if len(lines) == 0 {
+		return 0, nil
+	}
+	center := float64(width) / 2
+	span := 2 * math.Min(center-left, right-center)
+	y, err := p.layoutTitle(h, dc, lines[0], center, 0.5, math.Min(float64(width)*0.9, span), fontColor)
+	if err != nil {
+		return 0, err
+	}
+	if len(lines) == 1 {
+		return y, nil
+	}
+
+	y += titleGap
+	if err := p.setFont(dc, metadataSize); err != nil {
+		return 0, err
+	}
+	for _, text := range lines[1:] {
+		h.texts = append(h.texts, headerText{
+			line:         fitText(dc, text, math.Min(float64(width)*0.95, span)),
+			size:         metadataSize,
+			x:            center,
+			y:            y + float64(metadataLineSpacing)/2,
+			ax:           0.5,
+			bottom:       y + metadataLineSpacing,
+			color:        fontColor,
+			shadowOffset: 1,
+		})
+		y += metadataLineSpacing
+	}
+	return y, nil
+}
+
+// layoutLeftHeader left-aligns the text at left and shows a poster frame
+// before it, as tall as the text block.
+func (p *Processor) layoutLeftHeader(h *header, dc *gg.Context, lines []string, left, right float64, fontColor color.NRGBA, poster image.Image) (float64, error) {
+	if len(lines) == 0 {
+		return 0, nil
+	}
+
+	textX := left
+	if poster != nil {
+		// The poster is sized for a one-line title and the metadata,
+		// or to fill a fixed header.
+		posterHeight := float64(titleSize)*textLineFactor + titleGap + float64((len(lines)-1)*metadataLineSpacing)
+		if p.Config.HeaderHeight > 0 {
+			posterHeight = float64(p.Config.HeaderHeight - 2*headerPadding)
+		}
+		bounds := poster.Bounds()
+		posterWidth := posterHeight * float64(bounds.Dx()) / float64(bounds.Dy())
+		if posterHeight >= 1 && posterWidth >= 1 && posterWidth < (right-left)/2 {
+			scaled := image.NewRGBA(image.Rect(0, 0, int(posterWidth), int(posterHeight)))
+			draw.CatmullRom.Scale(scaled, scaled.Bounds(), poster, bounds, draw.Src, nil)
+			h.poster = scaled
+			h.posterX = int(left)
+			h.posterY = headerPadding
+			textX += posterWidth + float64(p.Config.Padding)*2
+		}
+	}
+	maxWidth := right - textX
+
+	y, err := p.layoutTitle(h, dc, lines[0], textX, 0, maxWidth, fontColor)
+	if err != nil {
+		return 0, err
+	}
+	if len(lines) > 1 {
+		y += titleGap
+		if err := p.setFont(dc, metadataSize); err != nil {
+			return 0, err
+		}
+		for _, text := range lines[1:] {
+			h.texts = append(h.texts, headerText{
+				line:         fitText(dc, text, maxWidth),
+				size:         metadataSize,
+				x:            textX,
+				y:            y + float64(metadataLineSpacing)/2,
+				bottom:       y + metadataLineSpacing,
+				color:        fontColor,
+				shadowOffset: 1,
+			})
+			y += metadataLineSpacing
+		}
+	}
+
+	// Right-to-left lines align with the right edge of the text block.
+	for i := range h.texts {
+		if h.texts[i].line.rtl {
+			h.texts[i].x, h.texts[i].ax = textX+maxWidth, 1
+		}
+	}
+
+	if h.poster != nil {
+		y = math.Max(y, float64(h.posterY+h.poster.Bounds().Dy()))
+	}
+	return y, nil
+}
+
+// layoutTableHeader shows the title above a two-column table of keys and
+// values between left and right.
+func (p *Processor) layoutTableHeader(h *header, dc *gg.Context, lines []string, left, right float64, fontColor color.NRGBA) (float64, error) {
+	if len(lines) == 0 {
+		return 0, nil
+	}
+	maxWidth := right - left
+	y, err := p.layoutTitle(h, dc, lines[0], left, 0, maxWidth, fontColor)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := p.headerTable(lines[1:])
+	if len(rows) == 0 {
+		return y, nil
+	}
+	keyColor := withOpacity(fontColor, tableKeyOpacity)
+	if err := p.setFont(dc, tableSize); err != nil {
+		return 0, err
+	}
+
+	var keyWidth float64
+	for _, row := range rows {
+		w, _ := dc.MeasureString(shapeArabic(row[0]))
+		keyWidth = math.Max(keyWidth, w)
+	}
+	keyWidth = math.Min(keyWidth, maxWidth/3)
+	valueX := left + keyWidth + 16
+
+	y += titleGap / 2
+	for _, row := range rows {
+		center := y + tableRowHeight/2
+		if row[0] != "" {
+			h.texts = append(h.texts, headerText{
+				line:         fitText(dc, row[0], keyWidth),
+				size:         tableSize,
+				x:            left + keyWidth,
+				y:            center,
+				ax:           1,
+				bottom:       y + tableRowHeight,
+				color:        keyColor,
+				shadowOffset: 1,
+			})
+		}
+		h.texts = append(h.texts, headerText{
+			line:         fitText(dc, row[1], left+maxWidth-valueX),
+			size:         tableSize,
+			x:            valueX,
+			y:            center,
+			bottom:       y + tableRowHeight,
+			color:        fontColor,
+			shadowOffset: 1,
+		})
+		y += tableRowHeight
+	}
+	return y, nil
+}
+
+// headerTable returns the key/value rows of the table style. Template
+// lines are split at their first ": "; without a template the rows list
+// the video properties.
+func (p *Processor) headerTable(lines []string) [][2]string {
+	var rows [][2]string
+	add := func(key, value string) {
+		if value != "" {
+			rows = append(rows, [2]string{key, value})
+		}
+	}
+
+	if p.headerTemplate != nil {
+		for _, line := range lines {
+			if key, value, ok := strings.Cut(line, ": "); ok {
+				add(key, value)
+			} else {
+				add("", line)
+			}
+		}
+		return rows
+	}
+
+	info := p.VideoInfo
+	add("Duration", formatDuration(info.Duration))
+	add("Size", humanSize(info.FileSize))
+	if !info.AudioOnly {
+		add("Resolution", p.formatDimensions())
+		add("Frame rate", formatFrameRate(info.AvgFrameRate)+" FPS")
+	}
+	add("Bit rate", p.formatBitRate())
+	if info.VideoCodec != "" {
+		add("Video", strings.TrimSpace(strings.ToUpper(info.VideoCodec)+" "+p.formatVideoDetails()))
+	}
+	add("Audio", p.formatAudioTracks())
+	add("Subtitles", p.formatSubtitleTracks())
+	if info.FormatLongName != "" {
+		add("Container", info.FormatLongName)
+	} else {
+		add("Container", info.FormatName)
+	}
+	add("Created", p.formatCreationDate())
+	return rows
+}
+
+// draw draws the header with its top edge at top.
+func (h *header) draw(p *Processor, dc *gg.Context, top int) error {
+	if h.poster != nil {
+		dc.DrawImage(h.poster, h.posterX, top+h.posterY)
+	}
+	if h.logo != nil {
+		dc.DrawImage(h.logo, h.logoX, top+h.logoY)
+	}
+	for _, t := range h.texts {
+		if err := p.setFont(dc, t.size); err != nil {
+			return err
+		}
+		y := float64(top) + t.y
+		dc.SetColor(h.shadow)
+		dc.DrawStringAnchored(t.line.text, t.x+t.shadowOffset, y+t.shadowOffset, t.ax, 0.5)
+		dc.SetColor(t.color)
+		dc.DrawStringAnchored(t.line.text, t.x, y, t.ax, 0.5)
+	}
+	return nil
+}
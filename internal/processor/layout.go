@@ -0,0 +1,110 @@
+package processor
+
+import (
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+// ellipsis marks text that was cut to fit.
+const ellipsis = "…"
+
+// textLine is one line of laid out text, ready to draw.
+type textLine struct {
+	// text is in display order, with Arabic letters joined.
+	text string
+	// rtl is set for right-to-left lines, which align to the right.
+	rtl bool
+}
+
+// layoutText breaks text into lines no wider than maxWidth in the current
+// font, at spaces where possible and between any characters otherwise (as
+// CJK text has no spaces). If there are more than maxLines lines, the last
+// one is cut with an ellipsis; maxLines <= 0 means no limit.
+func layoutText(dc *gg.Context, text string, maxWidth float64, maxLines int) []textLine {
+	measure := func(s string) float64 {
+		w, _ := dc.MeasureString(shapeArabic(s))
+		return w
+	}
+
+	lines := wrapText(measure, text, maxWidth)
+	if maxLines > 0 && len(lines) > maxLines {
+		rest := strings.Join(lines[maxLines-1:], " ")
+		lines = append(lines[:maxLines-1], ellipsize(measure, rest, maxWidth, true))
+	}
+
+	out := make([]textLine, len(lines))
+	for i, line := range lines {
+		out[i].text, out[i].rtl = visualOrder(shapeArabic(line))
+	}
+	return out
+}
+
+// fitText lays out text as a single line, cut with an ellipsis if it is
+// wider than maxWidth.
+func fitText(dc *gg.Context, text string, maxWidth float64) textLine {
+	lines := layoutText(dc, text, maxWidth, 1)
+	if len(lines) == 0 {
+		return textLine{}
+	}
+	return lines[0]
+}
+
+// wrapText greedily fills lines with words, breaking words that are wider
+// than a line on their own.
+func wrapText(measure func(string) float64, text string, maxWidth float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if measure(candidate) <= maxWidth {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+
+		runes := []rune(word)
+		for len(runes) > 1 && measure(string(runes)) > maxWidth {
+			n := fitRunes(measure, runes, maxWidth)
+			lines = append(lines, string(runes[:n]))
+			runes = runes[n:]
+		}
+		line = string(runes)
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fitRunes returns how many leading runes fit in maxWidth, at least one.
+func fitRunes(measure func(string) float64, runes []rune, maxWidth float64) int {
+	n := 1
+	for n < len(runes) && measure(string(runes[:n+1])) <= maxWidth {
+		n++
+	}
+	return n
+}
+
+// ellipsize cuts text with an ellipsis so that it fits maxWidth. With
+// force set the ellipsis is added even if the text fits, because more text
+// follows.
+func ellipsize(measure func(string) float64, text string, maxWidth float64, force bool) string {
+	if !force && measure(text) <= maxWidth {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		candidate := strings.TrimSpace(string(runes)) + ellipsis
+		if measure(candidate) <= maxWidth {
+			return candidate
+		}
+		runes = runes[:len(runes)-1]
+	}
+	return ellipsis
+}
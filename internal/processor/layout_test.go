@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/fogleman/gg"
+)
+
+// runeWidth measures text as one unit per character.
+func runeWidth(s string) float64 {
+	return float64(utf8.RuneCountInString(s))
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text     string
+		maxWidth float64
+		want     []string
+	}{
+		{"the quick brown fox", 10, []string{"the quick", "brown fox"}},
+		{"  spaced   out  ", 20, []string{"spaced out"}},
+		{"", 10, nil},
+		// Words wider than a line are broken.
+		{"abcdefghijkl", 5, []string{"abcde", "fghij", "kl"}},
+		{"ab abcdefgh", 5, []string{"ab", "abcde", "fgh"}},
+		// CJK text has no spaces.
+		{"一二三四五六七", 3, []string{"一二三", "四五六", "七"}},
+		// Right-to-left text is wrapped in logical order.
+		{"שלום עולם יפה", 9, []string{"שלום עולם", "יפה"}},
+	}
+	for _, tt := range tests {
+		if got := wrapText(runeWidth, tt.text, tt.maxWidth); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrapText(%q, %v) = %q, want %q", tt.text, tt.maxWidth, got, tt.want)
+		}
+	}
+}
+
+func TestEllipsize(t *testing.T) {
+	tests := []struct {
+		text     string
+		maxWidth float64
+		force    bool
+		want     string
+	}{
+		{"short", 8, false, "short"},
+		{"short", 8, true, "short…"},
+		{"hello world", 8, false, "hello w…"},
+		// Spaces before the ellipsis are dropped.
+		{"hello world", 7, false, "hello…"},
+		{"abc", 0, false, "…"},
+		{"שלום עולם", 6, false, "שלום…"},
+	}
+	for _, tt := range tests {
+		if got := ellipsize(runeWidth, tt.text, tt.maxWidth, tt.force); got != tt.want {
+			t.Errorf("ellipsize(%q, %v, %v) = %q, want %q", tt.text, tt.maxWidth, tt.force, got, tt.want)
+		}
+	}
+}
+
+func TestLayoutText(t *testing.T) {
+	// The default face of a gg context is 7 pixels wide per character.
+	dc := gg.NewContext(1, 1)
+	const char = 7
+
+	tests := []struct {
+		text     string
+		maxChars int
+		maxLines int
+		want     []textLine
+	}{
+		{"the quick brown fox", 10, 0, []textLine{{"the quick", false}, {"brown fox", false}}},
+		{"the quick brown fox", 10, 1, []textLine{{"the quick…", false}}},
+		// Right-to-left lines are cut at their logical end, which is shown
+		// on the left.
+		{"שלום עולם יפה", 9, 0, []textLine{{"םלוע םולש", true}, {"הפי", true}}},
+		{"שלום עולם יפה", 9, 1, []textLine{{"…לוע םולש", true}}},
+		{"file_עברית_2024.mp4", 20, 1, []textLine{{"file_2024_תירבע.mp4", false}}},
+		{"file_עברית_2024.mp4", 12, 1, []textLine{{"file_תירבע_…", false}}},
+	}
+	for _, tt := range tests {
+		got := layoutText(dc, tt.text, float64(tt.maxChars*char), tt.maxLines)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("layoutText(%q, %d chars, %d lines) = %+v, want %+v", tt.text, tt.maxChars, tt.maxLines, got, tt.want)
+		}
+	}
+}
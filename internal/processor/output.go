@@ -0,0 +1,145 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xi-mad/MontageGo/internal/csscolor"
+)
+
+// outputFormat returns the format the montage is written in: --format, or
+// else the one matching the output file extension, JPEG by default.
+func (p *Processor) outputFormat() (string, error) {
+	switch format := strings.ToLower(p.Config.OutputFormat); format {
+	case "", "auto":
+		return imageFormat(p.Config.OutputPath), nil
+	case "jpeg", "jpg":
+		return "jpeg", nil
+	case "png", "webp":
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported output format: %s", p.Config.OutputFormat)
+	}
+}
+
+// imageFormat picks the image format from the extension of path.
+func imageFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return "png"
+	case ".webp":
+		return "webp"
+	}
+	return "jpeg"
+}
+
+// checkColors parses every color option up front, so that a mistake is
+// reported with its flag before any frames are extracted. It also makes
+// sure a transparent background goes to a format that can keep it.
+func (p *Processor) checkColors(format string) error {
+	options := []struct{ flag, value string }{
+		{"font-color", p.Config.FontColor},
+		{"shadow-color", p.Config.ShadowColor},
+		{"bg-color", p.Config.BackgroundColor},
+		{"timestamp-color", p.Config.TimestampColor},
+		{"timestamp-bg-color", p.Config.TimestampBgColor},
+		{"tile-border-color", p.Config.TileBorderColor},
+		{"tile-shadow-color", p.Config.TileShadowColor},
+	}
+	if p.Config.BackgroundGradient != "" && p.Config.BackgroundGradient != "none" {
+		options = append(options, struct{ flag, value string }{"bg-gradient-to", p.Config.BackgroundGradientTo})
+	}
+
+	transparent := ""
+	for _, opt := range options {
+		// Empty values fall back to another option or go unused.
+		if opt.value == "" {
+			continue
+		}
+		c, err := csscolor.Parse(opt.value)
+		if err != nil {
+			return fmt.Errorf("invalid --%s: %w", opt.flag, err)
+		}
+		if (opt.flag == "bg-color" || opt.flag == "bg-gradient-to") && c.A < 255 && transparent == "" {
+			transparent = opt.flag
+		}
+	}
+
+	// A frame background covers the canvas, so it is opaque whatever the
+	// color beneath.
+	if transparent != "" && !p.Config.BackgroundFrame && format == "jpeg" {
+		return fmt.Errorf("--%s is transparent, which needs PNG or WebP output (give --output a .png or .webp name, or use --format)", transparent)
+	}
+	return nil
+}
+
+// saveImage writes img to path, or to stdout for "-", in the given format.
+func (p *Processor) saveImage(img image.Image, path, format string) error {
+	if format == "webp" {
+		return p.encodeWebP(img, path)
+	}
+
+	encode := func(w io.Writer) error {
+		if format == "png" {
+			return png.Encode(w, img)
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: p.jpegQuality()})
+	}
+	if path == "-" {
+		return encode(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := encode(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// encodeWebP converts img to WebP with ffmpeg's libwebp encoder, keeping
+// its alpha channel, and writes it to path or to stdout for "-".
+func (p *Processor) encodeWebP(img image.Image, path string) error {
+	var in bytes.Buffer
+	// The PNG only travels through the pipe, so speed beats size.
+	enc := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := enc.Encode(&in, img); err != nil {
+		return err
+	}
+
+	out := path
+	if path == "-" {
+		out = "pipe:1"
+	}
+	args := []string{
+		"-hide_banner", "-loglevel", "error",
+		"-f", "png_pipe", "-i", "pipe:0",
+		"-c:v", "libwebp",
+		"-quality", strconv.Itoa(p.jpegQuality()),
+		"-f", "webp",
+		"-y", out,
+	}
+	cmd := exec.Command(p.Config.FfmpegPath, args...)
+	cmd.Stdin = &in
+	if path == "-" {
+		cmd.Stdout = os.Stdout
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to encode WebP with ffmpeg (it needs libwebp): %w\nStderr: %s", err, stderr.String())
+	}
+	return nil
+}
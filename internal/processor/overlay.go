@@ -0,0 +1,193 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	_ "image/png" // logos and watermarks are usually PNGs with alpha
+	"math"
+	"os"
+
+	"github.com/fogleman/gg"
+	"golang.org/x/image/draw"
+)
+
+const (
+	// logoGap is the space between the header logo and the header text.
+	logoGap = 16
+	// watermarkInset is the distance between a watermark in a corner and
+	// the edges of the tile or canvas.
+	watermarkInset = 8
+)
+
+// loadOverlay decodes a logo, watermark or background image, keeping its
+// alpha channel.
+func loadOverlay(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// scaleOverlay resizes an overlay image to w x h and fades it to the given
+// opacity. Bilinear scaling does not overshoot, so the transparent edges of
+// logos stay clean.
+func scaleOverlay(img image.Image, w, h int, opacity float64) *image.RGBA {
+	scaled := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.BiLinear.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+	if opacity < 1 {
+		// The pixels are premultiplied, so all channels fade together.
+		for i, v := range scaled.Pix {
+			scaled.Pix[i] = uint8(float64(v)*opacity + 0.5)
+		}
+	}
+	return scaled
+}
+
+// checkOpacity returns an error if an overlay opacity is outside 0-1.
+func checkOpacity(name string, opacity float64) error {
+	if opacity < 0 || opacity > 1 {
+		return fmt.Errorf("%s opacity must be between 0 and 1", name)
+	}
+	return nil
+}
+
+// layoutLogo scales the header logo and places it at the left or right
+// margin. Its vertical position is set once the header height is known.
+func (p *Processor) layoutLogo(h *header, width int) error {
+	if p.Config.LogoScale <= 0 {
+		return fmt.Errorf("logo scale must be positive")
+	}
+	bounds := p.logo.Bounds()
+	logoWidth := float64(bounds.Dx()) * p.Config.LogoScale
+	logoHeight := float64(bounds.Dy()) * p.Config.LogoScale
+	// A fixed header height limits the size of the logo.
+	if p.Config.HeaderHeight > 0 {
+		if maxHeight := float64(p.Config.HeaderHeight - 2*headerPadding); logoHeight > maxHeight {
+			logoWidth *= maxHeight / logoHeight
+			logoHeight = maxHeight
+		}
+	}
+	if logoWidth < 1 || logoHeight < 1 {
+		return nil
+	}
+
+	h.logo = scaleOverlay(p.logo, int(logoWidth), int(logoHeight), p.Config.LogoOpacity)
+	switch p.Config.LogoPosition {
+	case "", "right":
+		h.logoX = width - p.Config.Margin - h.logo.Bounds().Dx()
+	case "left":
+		h.logoX = p.Config.Margin
+	default:
+		return fmt.Errorf("unsupported logo position: %s", p.Config.LogoPosition)
+	}
+	return nil
+}
+
+// watermark stamps an image over each tile or over the whole canvas.
+type watermark struct {
+	img      image.Image
+	target   string
+	position string
+	// scale is the width of the watermark relative to the tile or canvas.
+	scale   float64
+	opacity float64
+	// scaled caches the watermark resized for each target width.
+	scaled map[int]*image.RGBA
+}
+
+// newWatermark loads the configured watermark, or returns nil if there is
+// none.
+func (p *Processor) newWatermark() (*watermark, error) {
+	if p.Config.Watermark == "" {
+		return nil, nil
+	}
+	switch p.Config.WatermarkTarget {
+	case "", "tiles", "canvas":
+	default:
+		return nil, fmt.Errorf("unsupported watermark target: %s", p.Config.WatermarkTarget)
+	}
+	switch p.Config.WatermarkPosition {
+	case "", "top-left", "top-right", "bottom-left", "bottom-right", "center", "tiled":
+	default:
+		return nil, fmt.Errorf("unsupported watermark position: %s", p.Config.WatermarkPosition)
+	}
+	if p.Config.WatermarkScale <= 0 || p.Config.WatermarkScale > 1 {
+		return nil, fmt.Errorf("watermark scale must be greater than 0 and at most 1")
+	}
+	if err := checkOpacity("watermark", p.Config.WatermarkOpacity); err != nil {
+		return nil, err
+	}
+
+	img, err := loadOverlay(p.Config.Watermark)
+	if err != nil {
+		return nil, err
+	}
+	return &watermark{
+		img:      img,
+		target:   p.Config.WatermarkTarget,
+		position: p.Config.WatermarkPosition,
+		scale:    p.Config.WatermarkScale,
+		opacity:  p.Config.WatermarkOpacity,
+		scaled:   make(map[int]*image.RGBA),
+	}, nil
+}
+
+// onTiles reports whether the watermark is drawn over each tile rather than
+// once over the canvas.
+func (w *watermark) onTiles() bool {
+	return w.target == "" || w.target == "tiles"
+}
+
+// stamp returns a copy of a tile with the watermark drawn over it, so that
+// rounded tile corners cut the watermark as well.
+func (w *watermark) stamp(img image.Image) image.Image {
+	bounds := img.Bounds()
+	tile := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(tile, tile.Bounds(), img, bounds.Min, draw.Src)
+	w.draw(gg.NewContextForRGBA(tile), 0, 0, bounds.Dx(), bounds.Dy())
+	return tile
+}
+
+// draw stamps the watermark over the given area of the canvas.
+func (w *watermark) draw(dc *gg.Context, x, y, width, height int) {
+	mark, ok := w.scaled[width]
+	if !ok {
+		bounds := w.img.Bounds()
+		markWidth := math.Max(1, math.Round(float64(width)*w.scale))
+		markHeight := math.Max(1, math.Round(markWidth*float64(bounds.Dy())/float64(bounds.Dx())))
+		mark = scaleOverlay(w.img, int(markWidth), int(markHeight), w.opacity)
+		w.scaled[width] = mark
+	}
+	mw, mh := mark.Bounds().Dx(), mark.Bounds().Dy()
+
+	switch w.position {
+	case "tiled":
+		// Repeat the mark with half its size between copies, clipped to
+		// the area.
+		dc.DrawRectangle(float64(x), float64(y), float64(width), float64(height))
+		dc.Clip()
+		for ty := y + watermarkInset; ty < y+height; ty += mh + mh/2 + 1 {
+			for tx := x + watermarkInset; tx < x+width; tx += mw + mw/2 + 1 {
+				dc.DrawImage(mark, tx, ty)
+			}
+		}
+		dc.ResetClip()
+	case "top-left":
+		dc.DrawImage(mark, x+watermarkInset, y+watermarkInset)
+	case "top-right":
+		dc.DrawImage(mark, x+width-watermarkInset-mw, y+watermarkInset)
+	case "bottom-left":
+		dc.DrawImage(mark, x+watermarkInset, y+height-watermarkInset-mh)
+	case "center":
+		dc.DrawImage(mark, x+(width-mw)/2, y+(height-mh)/2)
+	default: // bottom-right
+		dc.DrawImage(mark, x+width-watermarkInset-mw, y+height-watermarkInset-mh)
+	}
+}
@@ -0,0 +1,194 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/fogleman/gg"
+	"github.com/xi-mad/MontageGo/internal/csscolor"
+)
+
+const (
+	// paletteSamplesPerFrame caps how many pixels of each frame feed k-means.
+	paletteSamplesPerFrame = 2000
+	// paletteIterations is the maximum number of k-means refinement rounds.
+	paletteIterations = 20
+	// paletteSwatchHeight is the height of the swatch row in the header.
+	paletteSwatchHeight = 48
+)
+
+// PaletteColor is one dominant color of the video and the share of sampled
+// pixels it represents.
+type PaletteColor struct {
+	Hex    string  `json:"hex"`
+	Weight float64 `json:"weight"`
+}
+
+// extractPalette clusters pixels sampled from the extracted frames with
+// k-means and returns the k cluster centers, most common first.
+func extractPalette(frames []image.Image, k int) []PaletteColor {
+	var samples [][3]float64
+	for _, img := range frames {
+		if img == nil {
+			continue
+		}
+		b := img.Bounds()
+		step := int(math.Ceil(math.Sqrt(float64(b.Dx()*b.Dy()) / paletteSamplesPerFrame)))
+		if step < 1 {
+			step = 1
+		}
+		for y := b.Min.Y; y < b.Max.Y; y += step {
+			for x := b.Min.X; x < b.Max.X; x += step {
+				c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+				samples = append(samples, [3]float64{float64(c.R), float64(c.G), float64(c.B)})
+			}
+		}
+	}
+	if len(samples) == 0 || k <= 0 {
+		return nil
+	}
+	if k > len(samples) {
+		k = len(samples)
+	}
+
+	// A fixed seed keeps the palette stable between runs on the same input.
+	rng := rand.New(rand.NewSource(1))
+	centers := seedCenters(samples, k, rng)
+	assignments := make([]int, len(samples))
+
+	for iter := 0; iter < paletteIterations; iter++ {
+		changed := false
+		for i, s := range samples {
+			nearest := nearestCenter(s, centers)
+			if iter == 0 || nearest != assignments[i] {
+				assignments[i] = nearest
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		sums := make([][3]float64, k)
+		counts := make([]int, k)
+		for i, s := range samples {
+			c := assignments[i]
+			sums[c][0] += s[0]
+			sums[c][1] += s[1]
+			sums[c][2] += s[2]
+			counts[c]++
+		}
+		for c := range centers {
+			if counts[c] == 0 {
+				continue // Keep empty clusters where they are.
+			}
+			n := float64(counts[c])
+			centers[c] = [3]float64{sums[c][0] / n, sums[c][1] / n, sums[c][2] / n}
+		}
+	}
+
+	counts := make([]int, k)
+	for _, c := range assignments {
+		counts[c]++
+	}
+
+	palette := make([]PaletteColor, 0, k)
+	for c, center := range centers {
+		if counts[c] == 0 {
+			continue
+		}
+		palette = append(palette, PaletteColor{
+			Hex:    fmt.Sprintf("#%02X%02X%02X", uint8(math.Round(center[0])), uint8(math.Round(center[1])), uint8(math.Round(center[2]))),
+			Weight: float64(counts[c]) / float64(len(samples)),
+		})
+	}
+	sort.SliceStable(palette, func(i, j int) bool {
+		return palette[i].Weight > palette[j].Weight
+	})
+
+	return palette
+}
+
+// seedCenters picks initial centers with k-means++ seeding.
+func seedCenters(samples [][3]float64, k int, rng *rand.Rand) [][3]float64 {
+	centers := make([][3]float64, 0, k)
+	centers = append(centers, samples[rng.Intn(len(samples))])
+
+	dists := make([]float64, len(samples))
+	for len(centers) < k {
+		total := 0.0
+		for i, s := range samples {
+			dists[i] = colorDistance(s, centers[nearestCenter(s, centers)])
+			total += dists[i]
+		}
+		if total == 0 {
+			// Fewer distinct colors than k; duplicate the first center.
+			centers = append(centers, centers[0])
+			continue
+		}
+		target := rng.Float64() * total
+		for i, d := range dists {
+			target -= d
+			if target <= 0 {
+				centers = append(centers, samples[i])
+				break
+			}
+		}
+		if target > 0 {
+			centers = append(centers, samples[len(samples)-1])
+		}
+	}
+
+	return centers
+}
+
+// nearestCenter returns the index of the center closest to s.
+func nearestCenter(s [3]float64, centers [][3]float64) int {
+	best := 0
+	bestDist := math.MaxFloat64
+	for i, c := range centers {
+		if d := colorDistance(s, c); d < bestDist {
+			best = i
+			bestDist = d
+		}
+	}
+	return best
+}
+
+// colorDistance is the squared euclidean distance between two RGB colors.
+func colorDistance(a, b [3]float64) float64 {
+	dr := a[0] - b[0]
+	dg := a[1] - b[1]
+	db := a[2] - b[2]
+	return dr*dr + dg*dg + db*db
+}
+
+// drawPalette paints the palette as equally sized swatches filling the given
+// rectangle, labeled with their hex codes in the current font.
+func drawPalette(dc *gg.Context, palette []PaletteColor, x, y, width, height int) {
+	for i, pc := range palette {
+		c, err := csscolor.Parse(pc.Hex)
+		if err != nil {
+			continue
+		}
+		x0 := x + i*width/len(palette)
+		x1 := x + (i+1)*width/len(palette)
+		dc.SetColor(c)
+		dc.DrawRectangle(float64(x0), float64(y), float64(x1-x0), float64(height))
+		dc.Fill()
+
+		// Pick black or white text depending on the swatch brightness.
+		r, g, b, _ := c.RGBA()
+		luma := 0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(b>>8)
+		if luma > 140 {
+			dc.SetColor(color.Black)
+		} else {
+			dc.SetColor(color.White)
+		}
+		dc.DrawStringAnchored(pc.Hex, float64(x0+x1)/2, float64(y)+float64(height)/2, 0.5, 0.5)
+	}
+}
@@ -5,49 +5,59 @@ import (
 	"fmt"
 	"image"
 	"image/color"
-	"image/jpeg"
+	"math"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/fogleman/gg"
+	"github.com/xi-mad/MontageGo/internal/csscolor"
 	"github.com/xi-mad/MontageGo/internal/ffprobe"
+	"github.com/xi-mad/MontageGo/internal/fonts"
 	"github.com/xi-mad/MontageGo/pkg/config"
-)
-
-var (
-	colorNameToHex = map[string]string{
-		"black":     "#000000",
-		"white":     "#FFFFFF",
-		"red":       "#FF0000",
-		"lime":      "#00FF00",
-		"blue":      "#0000FF",
-		"yellow":    "#FFFF00",
-		"cyan":      "#00FFFF",
-		"magenta":   "#FF00FF",
-		"silver":    "#C0C0C0",
-		"gray":      "#808080",
-		"grey":      "#808080",
-		"maroon":    "#800000",
-		"olive":     "#808000",
-		"green":     "#008000",
-		"purple":    "#800080",
-		"teal":      "#008080",
-		"navy":      "#000080",
-		"darkgray":  "#A9A9A9",
-		"darkgrey":  "#A9A9A9",
-		"lightgray": "#D3D3D3",
-		"lightgrey": "#D3D3D3",
-	}
+	"golang.org/x/image/font"
 )
 
 type Processor struct {
 	Config    *config.Config
 	VideoInfo *ffprobe.VideoInfo
+
+	// barcode holds the per-frame colors of the movie barcode, if enabled.
+	barcode []color.RGBA
+	// palette holds the dominant colors of the extracted frames, if enabled.
+	palette []PaletteColor
+	// crop is the area of the source frames that is kept, nil for all of it.
+	crop *cropRect
+	// deinterlaceFilter is the ffmpeg deinterlacer to run, empty for none.
+	deinterlaceFilter string
+	// toneMapChain is the ffmpeg filter chain converting HDR frames to SDR.
+	// toneMapper is set instead when ffmpeg lacks the required filters.
+	toneMapChain string
+	toneMapper   *toneMapper
+	// filters caches the filter names supported by ffmpeg, see hasFilter.
+	filters map[string]bool
+	// headerTemplate renders the header text, nil for the built-in layout.
+	headerTemplate *template.Template
+	// timecoder turns tile times into SMPTE timecodes, nil for audio.
+	timecoder *timecoder
+	// captions holds the subtitle cues captioning the tiles, if enabled.
+	captions []subtitleCue
+	// subtitlesFilter burns subtitles into the frames, empty for none.
+	subtitlesFilter string
+	// subtitlesStart is the timestamp the burned-in cues count from: the
+	// container start time for a subtitle stream of the input, zero for a
+	// separate file.
+	subtitlesStart float64
+	// fonts draws all text, falling back per character to later fonts.
+	fonts *fonts.Chain
+	// logo is the image shown in the header, nil for none.
+	logo image.Image
+	// watermark is stamped over the tiles or the canvas, nil for none.
+	watermark *watermark
 }
 
 func New(cfg *config.Config, info *ffprobe.VideoInfo) *Processor {
@@ -59,90 +69,234 @@ func New(cfg *config.Config, info *ffprobe.VideoInfo) *Processor {
 
 // Run orchestrates the montage creation process.
 func (p *Processor) Run() error {
+	// Parse the header template first so mistakes fail fast.
+	tmpl, err := p.loadHeaderTemplate()
+	if err != nil {
+		return err
+	}
+	p.headerTemplate = tmpl
+
+	format, err := p.outputFormat()
+	if err != nil {
+		return err
+	}
+	if err := p.checkColors(format); err != nil {
+		return err
+	}
+	if err := p.checkBarcode(); err != nil {
+		return err
+	}
+	if err := p.checkBackground(); err != nil {
+		return err
+	}
+	if p.Config.PaletteHeader && p.Config.Palette <= 0 {
+		return fmt.Errorf("--palette-header needs --palette to set the number of colors")
+	}
+
+	primaryFont := p.Config.FontFile
+	if p.Config.Font != "" {
+		if primaryFont != "" {
+			return fmt.Errorf("font and font file cannot be used together")
+		}
+		primaryFont = p.Config.Font
+	}
+	p.fonts, err = fonts.Load(primaryFont, p.Config.FontFallbacks)
+	if err != nil {
+		return fmt.Errorf("failed to load fonts: %w", err)
+	}
+
+	if p.Config.Logo != "" {
+		if err := checkOpacity("logo", p.Config.LogoOpacity); err != nil {
+			return err
+		}
+		if p.logo, err = loadOverlay(p.Config.Logo); err != nil {
+			return fmt.Errorf("failed to load logo: %w", err)
+		}
+	}
+	if p.watermark, err = p.newWatermark(); err != nil {
+		return fmt.Errorf("failed to load watermark: %w", err)
+	}
+
+	captions, err := p.loadCaptions()
+	if err != nil {
+		return fmt.Errorf("failed to load captions: %w", err)
+	}
+	p.captions = captions
+
+	// Work out which part of the picture to keep before sizing the tiles,
+	// so that auto-height follows the cropped aspect ratio.
+	if !p.VideoInfo.AudioOnly {
+		crop, err := p.resolveCrop()
+		if err != nil {
+			return fmt.Errorf("failed to determine crop: %w", err)
+		}
+		p.crop = crop
+
+		if err := p.resolveDeinterlace(); err != nil {
+			return err
+		}
+		if err := p.resolveToneMapping(); err != nil {
+			return err
+		}
+		if p.subtitlesFilter, err = p.resolveBurnSubtitles(); err != nil {
+			return err
+		}
+
+		tc, err := p.newTimecoder()
+		if err != nil {
+			return err
+		}
+		p.timecoder = tc
+	}
+
+	if err := p.resolveLayout(); err != nil {
+		return err
+	}
+	// Chapter selection takes its frame count from the chapters rather than
+	// from the grid, so a missing column count would only show when the
+	// rows are worked out.
+	if p.Config.Columns <= 0 {
+		return fmt.Errorf("number of columns must be positive")
+	}
+
 	// Pre-calculate thumbnail dimensions, especially for auto-height.
 	thumbWidth := p.Config.ThumbWidth
 	thumbHeight := p.Config.ThumbHeight
 	if thumbHeight <= 0 {
-		// Ensure we don't divide by zero if video info is weird.
-		if p.VideoInfo.Height == 0 {
-			return fmt.Errorf("video height is 0, cannot auto-calculate thumbnail height")
+		if p.VideoInfo.AudioOnly {
+			// Audio has no aspect ratio of its own, use a widescreen tile.
+			thumbHeight = thumbWidth * 9 / 16
+		} else {
+			aspect := p.displayAspect()
+			// Ensure we don't divide by zero if video info is weird.
+			if aspect == 0 {
+				return fmt.Errorf("video height is 0, cannot auto-calculate thumbnail height")
+			}
+			thumbHeight = int(float64(thumbWidth) / aspect)
 		}
-		thumbHeight = int(float64(thumbWidth) / (float64(p.VideoInfo.Width) / float64(p.VideoInfo.Height)))
 	}
 
 	// 1. Calculate timestamps and extract frames in parallel into memory.
-	frames, timestamps, err := p.extractFrames(thumbWidth, thumbHeight)
+	// Audio-only inputs get a rendered spectrogram or waveform instead.
+	var frames []image.Image
+	var timestamps []float64
+	if p.VideoInfo.AudioOnly {
+		frames, timestamps, err = p.extractAudioFrames(thumbWidth, thumbHeight)
+	} else {
+		timestamps, err = p.tileTimestamps()
+		if err == nil {
+			frames, err = p.extractFrames(timestamps, thumbWidth, thumbHeight)
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("failed to extract frames: %w", err)
 	}
 
+	// Sample the movie barcode if it is shown in the sheet or saved on its own.
+	if !p.VideoInfo.AudioOnly && (p.barcodeInSheet() || p.Config.BarcodeOutput != "") {
+		p.barcode, err = p.sampleBarcodeColors()
+		if err != nil {
+			return fmt.Errorf("failed to sample barcode colors: %w", err)
+		}
+	}
+	if p.barcode != nil && p.Config.BarcodeOutput != "" {
+		gridWidth := p.Config.Columns*thumbWidth + (p.Config.Columns-1)*p.Config.Padding
+		if err := p.saveBarcode(p.barcode, gridWidth); err != nil {
+			return fmt.Errorf("failed to save barcode: %w", err)
+		}
+	}
+
+	if p.Config.Palette > 0 && !p.VideoInfo.AudioOnly {
+		p.palette = extractPalette(frames, p.Config.Palette)
+	}
+
 	// 2. Compose the final image using gg.
 	err = p.composeMontage(frames, timestamps, thumbWidth, thumbHeight)
 	if err != nil {
 		return fmt.Errorf("failed to compose montage: %w", err)
 	}
 
-	return nil
-}
-
-// extractFrames calculates timestamps and extracts video frames into memory.
-// This new version uses a single ffmpeg process to extract all frames at once
-// for much better efficiency than spawning a process per frame.
-func (p *Processor) extractFrames(thumbWidth, thumbHeight int) ([]image.Image, []float64, error) {
-	numFrames := p.Config.Columns * p.Config.Rows
-	if numFrames <= 0 {
-		return nil, nil, fmt.Errorf("number of frames must be positive")
+	// 3. Describe the result for other tools.
+	if p.Config.SidecarPath != "" {
+		if err := p.writeSidecar(timestamps); err != nil {
+			return fmt.Errorf("failed to write sidecar: %w", err)
+		}
 	}
 
-	// Use 90% of the video duration, skipping the first and last 5%.
-	duration := p.VideoInfo.Duration * 0.9
-	startOffset := p.VideoInfo.Duration * 0.05
-	interval := duration / float64(numFrames)
+	return nil
+}
 
-	timestamps := make([]float64, numFrames)
-	for i := 0; i < numFrames; i++ {
-		timestamps[i] = startOffset + (float64(i) * interval)
+// extractFrames extracts the video frames at the given ascending timestamps
+// into memory. This new version uses a single ffmpeg process to extract all
+// frames at once for much better efficiency than spawning a process per frame.
+func (p *Processor) extractFrames(timestamps []float64, thumbWidth, thumbHeight int) ([]image.Image, error) {
+	numFrames := len(timestamps)
+	if numFrames == 0 {
+		return nil, fmt.Errorf("number of frames must be positive")
 	}
+	startOffset := timestamps[0]
 
 	// --- Efficient frame extraction using a single ffmpeg process ---
 
 	// 1. Get video FPS.
-	var fps float64 = 25.0 // Default if not found
-	if p.VideoInfo.AvgFrameRate != "" {
-		parts := strings.Split(p.VideoInfo.AvgFrameRate, "/")
-		if len(parts) == 2 {
-			if num, err := strconv.ParseFloat(parts[0], 64); err == nil {
-				if den, err := strconv.ParseFloat(parts[1], 64); err == nil && den != 0 {
-					fps = num / den
-				}
-			}
-		}
-	}
+	fps := p.frameRate()
 
 	// 2. Generate the 'select' filter string based on frame numbers.
 	// We use -ss to seek, so timestamps for frames are relative to startOffset.
 	selectParts := make([]string, numFrames)
+	lastFrame := -1
 	for i := 0; i < numFrames; i++ {
-		relativeTimestamp := float64(i) * interval
+		relativeTimestamp := timestamps[i] - startOffset
 		frameNumber := int(relativeTimestamp * fps)
+		// Tiles closer together than one frame would select the same frame
+		// and leave ffmpeg a frame short, so take the next one instead.
+		if frameNumber <= lastFrame {
+			frameNumber = lastFrame + 1
+		}
+		lastFrame = frameNumber
 		// The comma in "eq(n,123)" must be escaped for the ffmpeg filter parser.
 		selectParts[i] = fmt.Sprintf("eq(n\\,%d)", frameNumber)
 	}
 	selectFilter := "select='" + strings.Join(selectParts, "+") + "'"
 
+	// The deinterlacer needs neighboring fields, so it has to see every frame
+	// before select drops the ones we don't want.
+	var filters []string
+	if p.deinterlaceFilter != "" {
+		filters = append(filters, p.deinterlaceFilter)
+	}
+	filters = append(filters, selectFilter)
+	if p.toneMapChain != "" {
+		filters = append(filters, p.toneMapChain)
+	}
+	if p.crop != nil {
+		filters = append(filters, p.crop.filter())
+	}
+	if p.subtitlesFilter != "" {
+		// Seeking with -ss restarts timestamps at zero, but the subtitles
+		// filter picks cues by timestamp. Burning in after the crop keeps
+		// subtitles placed in black bars on the tile.
+		filters = append(filters,
+			fmt.Sprintf("setpts=PTS+%.4f/TB", p.subtitlesStart+startOffset),
+			p.subtitlesFilter,
+		)
+	}
+	// Scaling to the exact tile size also undoes non-square pixels.
+	filters = append(filters, fmt.Sprintf("scale=%d:%d,setsar=1", thumbWidth, thumbHeight))
+
 	// 3. Construct the ffmpeg command.
 	// -ss is before -i for fast seeking.
 	// The output is a raw pipe of concatenated JPEG images.
-	args := []string{
-		"-ss", fmt.Sprintf("%.4f", startOffset),
-		"-i", p.VideoInfo.Path,
-		"-vf", fmt.Sprintf("%s,scale=%d:%d", selectFilter, thumbWidth, thumbHeight),
+	args := []string{"-ss", fmt.Sprintf("%.4f", startOffset)}
+	args = append(args, p.inputArgs()...)
+	args = append(args,
+		"-vf", strings.Join(filters, ","),
 		"-vframes", strconv.Itoa(numFrames),
 		"-q:v", fmt.Sprintf("%d", p.Config.JpegQuality),
 		"-f", "image2pipe",
 		"-c:v", "mjpeg",
 		"pipe:1",
-	}
+	)
 
 	cmd := exec.Command(p.Config.FfmpegPath, args...)
 	var out bytes.Buffer
@@ -151,7 +305,7 @@ func (p *Processor) extractFrames(thumbWidth, thumbHeight int) ([]image.Image, [
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return nil, nil, fmt.Errorf("failed to execute ffmpeg: %w\nStderr: %s", err, stderr.String())
+		return nil, fmt.Errorf("failed to execute ffmpeg: %w\nStderr: %s", err, stderr.String())
 	}
 
 	// 4. Decode the concatenated JPEG stream from stdout.
@@ -192,6 +346,9 @@ func (p *Processor) extractFrames(thumbWidth, thumbHeight int) ([]image.Image, [
 				errs <- fmt.Errorf("failed to decode frame %d: %w", index, err)
 				return
 			}
+			if p.toneMapper != nil {
+				img = p.toneMapper.apply(img)
+			}
 			frames[index] = img
 		}(frameIndex, imgData)
 
@@ -203,52 +360,124 @@ func (p *Processor) extractFrames(thumbWidth, thumbHeight int) ([]image.Image, [
 
 	// Check for any errors during decoding.
 	for err := range errs {
-		return nil, nil, err // Return on the first error.
+		return nil, err // Return on the first error.
 	}
 
 	// If we didn't find enough frames, it's an error.
 	if frameIndex != numFrames {
-		return nil, nil, fmt.Errorf("ffmpeg produced %d frames, but %d were expected. Stderr:\n%s", frameIndex, numFrames, stderr.String())
+		return nil, fmt.Errorf("ffmpeg produced %d frames, but %d were expected. Stderr:\n%s", frameIndex, numFrames, stderr.String())
 	}
 
-	return frames, timestamps, nil
+	return frames, nil
 }
 
 // composeMontage creates the final image by arranging the extracted frames.
 func (p *Processor) composeMontage(frames []image.Image, timestamps []float64, thumbWidth, thumbHeight int) error {
-	// Dimensions are now passed in.
+	timestampStyle, err := p.resolveTimestampStyle()
+	if err != nil {
+		return err
+	}
+	captionStyle, err := p.resolveCaptionStyle()
+	if err != nil {
+		return err
+	}
+	tileStyle, err := p.resolveTileStyle(thumbWidth, thumbHeight)
+	if err != nil {
+		return err
+	}
+
+	// Chapter selection, --count and --interval decide the frame count, so
+	// the number of rows follows from the frames rather than from --rows.
+	rows := (len(frames) + p.Config.Columns - 1) / p.Config.Columns
+
+	// Dimensions are now passed in. Each cell is a tile plus the labels
+	// drawn under it, if any.
+	cellHeight := thumbHeight + timestampStyle.belowHeight() + captionStyle.belowHeight()
 	gridWidth := p.Config.Columns*thumbWidth + (p.Config.Columns-1)*p.Config.Padding
-	gridHeight := p.Config.Rows*thumbHeight + (p.Config.Rows-1)*p.Config.Padding
+	gridHeight := rows*cellHeight + (rows-1)*p.Config.Padding
 
 	totalWidth := gridWidth + 2*p.Config.Margin
-	totalHeight := gridHeight + 2*p.Config.Margin + p.Config.HeaderHeight
+
+	// The "left" header style shows a frame from the middle of the video.
+	var poster image.Image
+	if p.Config.HeaderStyle == "left" && !p.VideoInfo.AudioOnly && len(frames) > 0 {
+		poster = frames[len(frames)/2]
+	}
+	hdr, err := p.layoutHeader(totalWidth, poster)
+	if err != nil {
+		return err
+	}
+	sheet, err := p.layoutSheet(hdr.height, gridHeight)
+	if err != nil {
+		return err
+	}
+	totalHeight, gridTop := sheet.height, sheet.gridTop
 
 	dc := gg.NewContext(totalWidth, totalHeight)
 
 	// Draw background
-	bgColor, err := parseHexColor(p.Config.BackgroundColor)
-	if err != nil {
-		return fmt.Errorf("invalid background color: %w", err)
+	if err := p.drawBackground(dc, frames); err != nil {
+		return err
 	}
-	dc.SetColor(bgColor)
-	dc.Clear()
 
 	// Draw header text
-	if p.Config.FontFile != "" {
-		if err := p.drawText(dc, totalWidth); err != nil {
-			return fmt.Errorf("failed to draw text: %w", err)
+	if err := hdr.draw(p, dc, sheet.headerTop); err != nil {
+		return fmt.Errorf("failed to draw text: %w", err)
+	}
+
+	if sheet.showBarcode {
+		drawBarcode(dc, p.barcode, p.Config.Margin, sheet.barcodeY, gridWidth, p.Config.BarcodeHeight)
+	}
+
+	if sheet.showPalette {
+		if err := p.setFont(dc, 14); err != nil {
+			return fmt.Errorf("could not load fontface for palette: %w", err)
 		}
+		drawPalette(dc, p.palette, p.Config.Margin, sheet.paletteY, gridWidth, paletteSwatchHeight)
 	}
 
-	// Prepare for drawing timestamps on frames
-	var timestampFontColor color.Color
-	var timestampShadowColor color.Color
-	if p.Config.FontFile != "" {
-		if err := dc.LoadFontFace(p.Config.FontFile, 18); err != nil {
+	// Prepare for drawing timestamps and chapter labels on frames. Both use
+	// the timestamp font size.
+	var chapterLabels []string
+	var labelColor, labelShadow color.Color
+	if p.Config.ChapterLabels && len(p.VideoInfo.Chapters) > 0 {
+		chapterLabels = p.chapterLabels(timestamps)
+		if labelColor, err = csscolor.Parse(p.Config.FontColor); err != nil {
+			return fmt.Errorf("invalid --font-color: %w", err)
+		}
+		if labelShadow, err = csscolor.Parse(p.Config.ShadowColor); err != nil {
+			return fmt.Errorf("invalid --shadow-color: %w", err)
+		}
+	}
+	var tileFace, captionFace font.Face
+	if timestampStyle != nil || chapterLabels != nil {
+		fontSize := float64(p.Config.TimestampSize)
+		if timestampStyle != nil {
+			fontSize = timestampStyle.fontSize
+		} else if fontSize <= 0 {
+			fontSize = 18
+		}
+		if tileFace, err = p.fonts.Face(fontSize); err != nil {
 			return fmt.Errorf("could not load fontface for timestamp: %w", err)
 		}
-		timestampFontColor, _ = parseHexColor("white")
-		timestampShadowColor, _ = parseHexColor("black")
+	}
+	if captionStyle != nil {
+		if captionFace, err = p.fonts.Face(captionStyle.fontSize); err != nil {
+			return fmt.Errorf("could not load fontface for captions: %w", err)
+		}
+	}
+	labelAtBottom := timestampStyle != nil && strings.HasPrefix(timestampStyle.position, "top")
+
+	tilePosition := func(i int) (int, int) {
+		row := i / p.Config.Columns
+		col := i % p.Config.Columns
+		return p.Config.Margin + col*(thumbWidth+p.Config.Padding), gridTop + row*(cellHeight+p.Config.Padding)
+	}
+	for i, img := range frames {
+		if img != nil {
+			x, y := tilePosition(i)
+			tileStyle.drawShadow(dc, x, y)
+		}
 	}
 
 	// Draw frames
@@ -256,32 +485,120 @@ func (p *Processor) composeMontage(frames []image.Image, timestamps []float64, t
 		if img == nil {
 			continue // Should not happen with current error handling, but good practice.
 		}
+		x, y := tilePosition(i)
 
-		row := i / p.Config.Columns
-		col := i % p.Config.Columns
-
-		x := p.Config.Margin + col*(thumbWidth+p.Config.Padding)
-		y := p.Config.HeaderHeight + p.Config.Margin + row*(thumbHeight+p.Config.Padding)
-
-		dc.DrawImage(img, x, y)
+		if p.watermark != nil && p.watermark.onTiles() {
+			img = p.watermark.stamp(img)
+		}
+		tileStyle.drawTile(dc, img, x, y)
+
+		if captionStyle != nil {
+			if caption := captionAt(p.captions, timestamps[i]); caption != "" {
+				// Captions sit under the timestamp row, or over the tile
+				// just above a timestamp in the bottom corners.
+				top := y + thumbHeight + timestampStyle.belowHeight()
+				if captionStyle.position == "over" {
+					top = y + thumbHeight - timestampStyle.bottomClearance()
+				}
+				dc.SetFontFace(captionFace)
+				captionStyle.draw(dc, caption, x, top, thumbWidth)
+			}
+		}
 
-		// Draw timestamp on the frame if font is available
-		if p.Config.FontFile != "" {
-			timestampStr := formatDuration(timestamps[i])
-			// Position is relative to the image's top-left corner
-			textX := float64(x + 10)
-			textY := float64(y + thumbHeight - 15)
+		if tileFace != nil {
+			dc.SetFontFace(tileFace)
+		}
+		if chapterLabels != nil && chapterLabels[i] != "" {
+			drawChapterLabel(dc, chapterLabels[i], labelAtBottom, labelColor, labelShadow, x, y, thumbWidth, thumbHeight)
+		}
 
-			dc.SetColor(timestampShadowColor)
-			dc.DrawStringAnchored(timestampStr, textX+1, textY+1, 0, 1)
-			dc.SetColor(timestampFontColor)
-			dc.DrawStringAnchored(timestampStr, textX, textY, 0, 1)
+		// Draw timestamp on the frame if enabled and a font is available
+		if timestampStyle != nil {
+			timestampStr := p.formatTimestamp(timestamps[i], timestampStyle.frameRate)
+			timestampStyle.draw(dc, timestampStr, x, y, thumbWidth, thumbHeight)
 		}
 	}
 
+	if p.watermark != nil && !p.watermark.onTiles() {
+		p.watermark.draw(dc, 0, 0, totalWidth, totalHeight)
+	}
+
 	// Save the final image
-	// The gg library's JPEG quality is 1-100 (higher is better),
-	// while ffmpeg's -q:v is 1-31 (lower is better). We'll do a rough conversion.
+	format, err := p.outputFormat()
+	if err != nil {
+		return err
+	}
+	return p.saveImage(dc.Image(), p.Config.OutputPath, format)
+}
+
+// sheetLayout is the vertical placement of the parts of the sheet.
+type sheetLayout struct {
+	// height is the height of the whole canvas.
+	height int
+	// headerTop, gridTop, barcodeY and paletteY are where the header, the
+	// grid, the barcode band and the palette band start.
+	headerTop int
+	gridTop   int
+	barcodeY  int
+	paletteY  int
+	// showBarcode and showPalette report whether the bands are drawn.
+	showBarcode bool
+	showPalette bool
+}
+
+// layoutSheet stacks the header, the barcode and palette bands and the grid
+// from top to bottom, given the heights of the header and the grid.
+func (p *Processor) layoutSheet(headerHeight, gridHeight int) (*sheetLayout, error) {
+	s := &sheetLayout{
+		showBarcode: p.barcode != nil && p.barcodeInSheet(),
+		showPalette: len(p.palette) > 0 && p.Config.PaletteHeader,
+	}
+
+	// A footer header goes below everything else, so the grid starts at the
+	// top margin.
+	switch p.Config.HeaderPosition {
+	case "", "top":
+		s.gridTop = headerHeight + p.Config.Margin
+	case "bottom":
+		s.gridTop = p.Config.Margin
+	default:
+		return nil, fmt.Errorf("unsupported header position: %s", p.Config.HeaderPosition)
+	}
+
+	// The barcode band sits between the header and the grid, or below the
+	// grid, separated from the tiles like another row.
+	barcodeBand := p.Config.BarcodeHeight + p.Config.Padding
+	if s.showBarcode && p.Config.Barcode == "header" {
+		s.barcodeY = s.gridTop
+		s.gridTop += barcodeBand
+	}
+
+	// The palette swatches form one more band at the bottom of the header.
+	if s.showPalette {
+		s.paletteY = s.gridTop
+		s.gridTop += paletteSwatchHeight + p.Config.Padding
+	}
+
+	// Only now that every band above the grid is in place is its bottom
+	// known.
+	bottom := s.gridTop + gridHeight
+	if s.showBarcode && p.Config.Barcode != "header" {
+		s.barcodeY = bottom + p.Config.Padding
+		bottom += barcodeBand
+	}
+
+	s.height = bottom + p.Config.Margin
+	if p.Config.HeaderPosition == "bottom" {
+		s.headerTop = s.height
+		s.height += headerHeight
+	}
+	return s, nil
+}
+
+// jpegQuality converts the configured quality to the encoder's scale.
+// The gg library's JPEG quality is 1-100 (higher is better),
+// while ffmpeg's -q:v is 1-31 (lower is better). We'll do a rough conversion.
+func (p *Processor) jpegQuality() int {
 	jpegQuality := 100 - (p.Config.JpegQuality-1)*3
 	if jpegQuality < 1 {
 		jpegQuality = 1
@@ -289,77 +606,59 @@ func (p *Processor) composeMontage(frames []image.Image, timestamps []float64, t
 	if jpegQuality > 100 {
 		jpegQuality = 100
 	}
-
-	if p.Config.OutputPath == "-" {
-		return jpeg.Encode(os.Stdout, dc.Image(), &jpeg.Options{Quality: jpegQuality})
-	} else {
-		return gg.SaveJPG(p.Config.OutputPath, dc.Image(), jpegQuality)
-	}
+	return jpegQuality
 }
 
-// drawText renders the header information onto the montage.
-func (p *Processor) drawText(dc *gg.Context, totalWidth int) error {
-	// Load font
-	if err := dc.LoadFontFace(p.Config.FontFile, 40); err != nil {
-		return fmt.Errorf("could not load fontface: %w", err)
-	}
+// barcodeInSheet reports whether the movie barcode is drawn on the montage.
+func (p *Processor) barcodeInSheet() bool {
+	return p.Config.Barcode == "header" || p.Config.Barcode == "footer"
+}
 
-	// Shadow color
-	shadowColor, err := parseHexColor(p.Config.ShadowColor)
+// setFont selects the font chain at the given size for the following text.
+func (p *Processor) setFont(dc *gg.Context, size float64) error {
+	face, err := p.fonts.Face(size)
 	if err != nil {
-		return fmt.Errorf("invalid shadow color: %w", err)
+		return fmt.Errorf("could not load fontface: %w", err)
 	}
+	dc.SetFontFace(face)
+	return nil
+}
 
-	// Font color
-	fontColor, err := parseHexColor(p.Config.FontColor)
-	if err != nil {
-		return fmt.Errorf("invalid font color: %w", err)
+// warnf reports a problem that does not stop the montage. It goes to
+// stderr, which stays free of image data, unless app logs are hidden.
+func (p *Processor) warnf(format string, args ...any) {
+	if p.Config.ShowAppLog {
+		fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
 	}
+}
 
-	// --- Draw Filename ---
-	filename := filepath.Base(p.VideoInfo.Path)
-	// Dynamically adjust font size to fit
-	fontSize := 40.0
-	for fontSize > 10 {
-		if err := dc.LoadFontFace(p.Config.FontFile, fontSize); err != nil {
-			return err
-		}
-		w, _ := dc.MeasureString(filename)
-		if w < float64(totalWidth)*0.9 {
-			break
-		}
-		fontSize -= 2
+// displayAspect returns the width/height ratio the (cropped) picture should
+// be shown at, honoring rotation and the sample aspect ratio. It returns 0 if
+// the dimensions are unknown.
+func (p *Processor) displayAspect() float64 {
+	info := p.VideoInfo
+	if info.DisplayWidth == 0 || info.DisplayHeight == 0 {
+		return 0
 	}
-	// Draw shadow then text
-	dc.SetColor(shadowColor)
-	dc.DrawStringAnchored(filename, float64(totalWidth)/2+2, 30+2, 0.5, 0.5)
-	dc.SetColor(fontColor)
-	dc.DrawStringAnchored(filename, float64(totalWidth)/2, 30, 0.5, 0.5)
-
-	// --- Draw Metadata Line 1 ---
-	if err := dc.LoadFontFace(p.Config.FontFile, 20); err != nil {
-		return err
+	aspect := float64(info.DisplayWidth) / float64(info.DisplayHeight)
+	if p.crop == nil {
+		return aspect
 	}
-	meta1 := p.formatMetadataLine1()
-	dc.SetColor(shadowColor)
-	dc.DrawStringAnchored(meta1, float64(totalWidth)/2+1, 80+1, 0.5, 0.5)
-	dc.SetColor(color.White) // A lighter color for metadata
-	dc.DrawStringAnchored(meta1, float64(totalWidth)/2, 80, 0.5, 0.5)
 
-	// --- Draw Metadata Line 2 ---
-	meta2 := p.formatMetadataLine2()
-	dc.SetColor(shadowColor)
-	dc.DrawStringAnchored(meta2, float64(totalWidth)/2+1, 105+1, 0.5, 0.5)
-	dc.SetColor(color.White)
-	dc.DrawStringAnchored(meta2, float64(totalWidth)/2, 105, 0.5, 0.5)
-
-	return nil
+	// The crop is in decoded frame pixels, which may not be square.
+	frameWidth, frameHeight := info.FrameSize()
+	pixelAspect := aspect / (float64(frameWidth) / float64(frameHeight))
+	return float64(p.crop.W) * pixelAspect / float64(p.crop.H)
 }
 
 // formatMetadataLine1 generates the first line of metadata: Resolution | FPS | Bitrate
+// For audio-only inputs it is: Sample rate | Channels | Bitrate
 func (p *Processor) formatMetadataLine1() string {
-	// Dimensions
-	dims := fmt.Sprintf("%dx%d", p.VideoInfo.Width, p.VideoInfo.Height)
+	if p.VideoInfo.AudioOnly {
+		return p.formatAudioMetadataLine1()
+	}
+
+	dims := p.formatDimensions()
 
 	// Frame rate
 	var fpsStr string
@@ -377,16 +676,30 @@ func (p *Processor) formatMetadataLine1() string {
 		fpsStr = "N/A FPS"
 	}
 
-	// Bitrate
-	var bitrateStr string
+	return fmt.Sprintf("%s | %s | %s", dims, fpsStr, p.formatBitRate())
+}
+
+// formatDimensions formats the frame size as decoded (upright), plus the
+// display aspect if pixels are not square and the HDR format.
+func (p *Processor) formatDimensions() string {
+	frameWidth, frameHeight := p.VideoInfo.FrameSize()
+	dims := fmt.Sprintf("%dx%d", frameWidth, frameHeight)
+	if frameWidth != p.VideoInfo.DisplayWidth && p.VideoInfo.DisplayAspectRatio != "" {
+		dims += fmt.Sprintf(" (DAR %s)", p.VideoInfo.DisplayAspectRatio)
+	}
+	if p.VideoInfo.HDRFormat != "" {
+		dims += " " + p.VideoInfo.HDRFormat
+	}
+	return dims
+}
+
+// formatBitRate formats the overall bitrate of the file in Mbps.
+func (p *Processor) formatBitRate() string {
 	if bitRate, err := strconv.ParseFloat(p.VideoInfo.BitRate, 64); err == nil {
 		bitrateMbps := bitRate / 1000000
-		bitrateStr = fmt.Sprintf("%.2f Mbps", bitrateMbps)
-	} else {
-		bitrateStr = "N/A Mbps"
+		return fmt.Sprintf("%.2f Mbps", bitrateMbps)
 	}
-
-	return fmt.Sprintf("%s | %s | %s", dims, fpsStr, bitrateStr)
+	return "N/A Mbps"
 }
 
 // formatMetadataLine2 generates the second line of metadata: Duration | File Size | Codecs
@@ -399,14 +712,102 @@ func (p *Processor) formatMetadataLine2() string {
 	sizeStr := fmt.Sprintf("%.2f MB", sizeMB)
 
 	// Codecs
-	codecs := strings.ToUpper(p.VideoInfo.VideoCodec)
+	var codecNames []string
+	if p.VideoInfo.VideoCodec != "" {
+		codecNames = append(codecNames, strings.ToUpper(p.VideoInfo.VideoCodec))
+	}
 	if p.VideoInfo.AudioCodec != "" {
-		codecs += " / " + strings.ToUpper(p.VideoInfo.AudioCodec)
+		codecNames = append(codecNames, strings.ToUpper(p.VideoInfo.AudioCodec))
 	}
+	codecs := strings.Join(codecNames, " / ")
 
 	return fmt.Sprintf("%s | %s | %s", durationStr, sizeStr, codecs)
 }
 
+// formatMetadataLine3 generates the stream details line:
+// Profile@Level pixel format bit depth color space | Audio tracks | Subtitles | Date
+func (p *Processor) formatMetadataLine3() string {
+	var parts []string
+	if video := p.formatVideoDetails(); video != "" {
+		parts = append(parts, video)
+	}
+	if audio := p.formatAudioTracks(); audio != "" {
+		parts = append(parts, "Audio: "+audio)
+	}
+	if subs := p.formatSubtitleTracks(); subs != "" {
+		parts = append(parts, "Subs: "+subs)
+	}
+	if created := p.formatCreationDate(); created != "" {
+		parts = append(parts, created)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// formatVideoDetails describes the video stream format, e.g.
+// "High@4.1 yuv420p 8-bit bt709".
+func (p *Processor) formatVideoDetails() string {
+	info := p.VideoInfo
+	var video []string
+	if info.VideoProfile != "" {
+		profile := info.VideoProfile
+		if info.VideoLevel != "" {
+			profile += "@" + info.VideoLevel
+		}
+		video = append(video, profile)
+	}
+	if info.PixelFormat != "" {
+		video = append(video, fmt.Sprintf("%s %d-bit", info.PixelFormat, info.BitDepth))
+	}
+	if info.ColorSpace != "" {
+		video = append(video, info.ColorSpace)
+	}
+	return strings.Join(video, " ")
+}
+
+// formatAudioTracks describes the audio tracks, e.g. "eng AAC stereo 48.0 kHz".
+func (p *Processor) formatAudioTracks() string {
+	var audio []string
+	for _, track := range p.VideoInfo.AudioTracks {
+		desc := strings.ToUpper(track.Codec)
+		if track.ChannelLayout != "" {
+			desc += " " + track.ChannelLayout
+		} else if track.Channels > 0 {
+			desc += fmt.Sprintf(" %dch", track.Channels)
+		}
+		if track.SampleRate > 0 {
+			desc += fmt.Sprintf(" %.1f kHz", float64(track.SampleRate)/1000)
+		}
+		if track.Language != "" {
+			desc = track.Language + " " + desc
+		}
+		audio = append(audio, desc)
+	}
+	return strings.Join(audio, ", ")
+}
+
+// formatSubtitleTracks lists the subtitle tracks by language, or by codec
+// if the language is unknown.
+func (p *Processor) formatSubtitleTracks() string {
+	var subs []string
+	for _, track := range p.VideoInfo.SubtitleTracks {
+		if track.Language != "" {
+			subs = append(subs, track.Language)
+		} else {
+			subs = append(subs, strings.ToUpper(track.Codec))
+		}
+	}
+	return strings.Join(subs, ", ")
+}
+
+// formatCreationDate returns the creation date of the file as YYYY-MM-DD,
+// or an empty string if unknown.
+func (p *Processor) formatCreationDate() string {
+	if created, err := time.Parse(time.RFC3339Nano, p.VideoInfo.CreationTime); err == nil {
+		return created.Format("2006-01-02")
+	}
+	return ""
+}
+
 // formatDuration formats a float64 of seconds into an HH:MM:SS string.
 func formatDuration(seconds float64) string {
 	d := time.Duration(seconds * float64(time.Second))
@@ -419,25 +820,9 @@ func formatDuration(seconds float64) string {
 	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
 }
 
-// parseHexColor converts a hex color string (e.g., "#RRGGBB") to a color.Color.
-func parseHexColor(s string) (color.Color, error) {
-	s = strings.ToLower(s)
-	if hex, ok := colorNameToHex[s]; ok {
-		s = hex
-	}
-
-	s = strings.TrimPrefix(s, "#")
-	if len(s) != 6 {
-		return color.Black, fmt.Errorf("invalid hex color format or unsupported color name: %s", s)
-	}
-	c, err := strconv.ParseInt(s, 16, 32)
-	if err != nil {
-		return color.Black, fmt.Errorf("failed to parse hex color: %w", err)
-	}
-	return color.RGBA{
-		R: uint8(c >> 16),
-		G: uint8(c >> 8),
-		B: uint8(c),
-		A: 255,
-	}, nil
+// withOpacity returns c with its alpha multiplied by opacity, which is
+// clamped to 0-1.
+func withOpacity(c color.NRGBA, opacity float64) color.NRGBA {
+	c.A = uint8(math.Round(float64(c.A) * math.Min(math.Max(opacity, 0), 1)))
+	return c
 }
@@ -0,0 +1,102 @@
+package processor
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/xi-mad/MontageGo/internal/ffprobe"
+	"github.com/xi-mad/MontageGo/pkg/config"
+)
+
+func TestLayoutSheet(t *testing.T) {
+	const (
+		headerHeight = 100
+		gridHeight   = 500
+		margin       = 20
+		padding      = 5
+		barcodeBand  = 40 + padding
+		paletteBand  = paletteSwatchHeight + padding
+	)
+	tests := []struct {
+		name           string
+		barcode        string
+		palette        bool
+		headerPosition string
+		want           sheetLayout
+	}{
+		{
+			name: "header only",
+			want: sheetLayout{height: 640, gridTop: 120},
+		},
+		{
+			name:    "header barcode",
+			barcode: "header",
+			want:    sheetLayout{height: 640 + barcodeBand, gridTop: 120 + barcodeBand, barcodeY: 120, showBarcode: true},
+		},
+		{
+			name:    "header barcode and palette",
+			barcode: "header",
+			palette: true,
+			want: sheetLayout{
+				height:   640 + barcodeBand + paletteBand,
+				gridTop:  120 + barcodeBand + paletteBand,
+				barcodeY: 120, paletteY: 120 + barcodeBand,
+				showBarcode: true, showPalette: true,
+			},
+		},
+		{
+			// The footer band must clear the grid, which the palette band
+			// pushes down.
+			name:    "footer barcode and palette",
+			barcode: "footer",
+			palette: true,
+			want: sheetLayout{
+				height:   640 + barcodeBand + paletteBand,
+				gridTop:  120 + paletteBand,
+				barcodeY: 120 + paletteBand + gridHeight + padding, paletteY: 120,
+				showBarcode: true, showPalette: true,
+			},
+		},
+		{
+			name:           "bottom header with footer barcode and palette",
+			barcode:        "footer",
+			palette:        true,
+			headerPosition: "bottom",
+			want: sheetLayout{
+				height:    640 + barcodeBand + paletteBand,
+				headerTop: 540 + barcodeBand + paletteBand,
+				gridTop:   20 + paletteBand,
+				barcodeY:  20 + paletteBand + gridHeight + padding, paletteY: 20,
+				showBarcode: true, showPalette: true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{
+			Margin:         margin,
+			Padding:        padding,
+			Barcode:        tt.barcode,
+			BarcodeHeight:  40,
+			PaletteHeader:  tt.palette,
+			HeaderPosition: tt.headerPosition,
+		}
+		p := New(cfg, &ffprobe.VideoInfo{})
+		if tt.barcode != "" {
+			p.barcode = []color.RGBA{{R: 255, A: 255}}
+		}
+		if tt.palette {
+			p.palette = []PaletteColor{{}}
+		}
+		got, err := p.layoutSheet(headerHeight, gridHeight)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("%s: layoutSheet() = %+v, want %+v", tt.name, *got, tt.want)
+		}
+		if got.showBarcode && got.barcodeY < got.gridTop+gridHeight && got.barcodeY+40 > got.gridTop {
+			t.Errorf("%s: barcode at %d overlaps the grid at %d-%d", tt.name, got.barcodeY, got.gridTop, got.gridTop+gridHeight)
+		}
+	}
+}
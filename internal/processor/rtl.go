@@ -0,0 +1,346 @@
+package processor
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+// visualOrder reorders a line of text from logical to display order with
+// the Unicode bidirectional algorithm, so that Arabic and Hebrew read right
+// to left while embedded Latin words and numbers keep their own order. It
+// also reports whether the line's base direction is right to left.
+func visualOrder(s string) (string, bool) {
+	if !hasRTL(s) {
+		return s, false
+	}
+	rtl := baseRTL(s)
+	runes, levels, err := bidiLevels(s, rtl)
+	if err != nil {
+		return s, false
+	}
+	return reorderLevels(runes, levels), rtl
+}
+
+// bidiLevels returns the runes of s with their embedding levels. The bidi
+// package resolves whether each run is left to right or right to left;
+// without explicit embeddings that settles the levels, except for the
+// numbers that rule I1 lifts to level 2 in a left-to-right line.
+func bidiLevels(s string, rtl bool) ([]rune, []int, error) {
+	var p bidi.Paragraph
+	if _, err := p.SetString(s); err != nil {
+		return nil, nil, err
+	}
+	o, err := p.Order()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var runes []rune
+	var levels []int
+	for i := 0; i < o.NumRuns(); i++ {
+		r := o.Run(i)
+		level := 0
+		switch {
+		case r.Direction() == bidi.RightToLeft:
+			level = 1
+		case rtl:
+			// Rule I2: left-to-right text in a right-to-left line.
+			level = 2
+		}
+		for _, c := range r.String() {
+			runes = append(runes, c)
+			levels = append(levels, level)
+		}
+	}
+	if !rtl {
+		raiseNumbers(runes, levels)
+	}
+	return runes, levels, nil
+}
+
+// raiseNumbers lifts the numbers of a left-to-right line that follow
+// right-to-left text from level 0 to 2 (rules W7 and I1), together with
+// the separators and terminators that belong to them (rules W4 and W5).
+func raiseNumbers(runes []rune, levels []int) {
+	classes := make([]bidi.Class, len(runes))
+	last := bidi.L
+	for i, r := range runes {
+		props, _ := bidi.LookupRune(r)
+		classes[i] = props.Class()
+		switch classes[i] {
+		case bidi.L, bidi.R, bidi.AL:
+			last = classes[i]
+		case bidi.AN:
+			levels[i] = 2
+		case bidi.EN:
+			if last != bidi.L {
+				levels[i] = 2
+			}
+		}
+	}
+
+	raised := func(i int) bool {
+		return i >= 0 && i < len(runes) && levels[i] == 2
+	}
+	for i := range runes {
+		if levels[i] != 0 {
+			continue
+		}
+		switch classes[i] {
+		case bidi.ES, bidi.CS:
+			// A single separator between two numbers, as in "1,5".
+			if raised(i-1) && raised(i+1) {
+				levels[i] = 2
+			}
+		case bidi.ET:
+			// Currency and percent signs around a number, as in "50%".
+			for j := i; j < len(runes) && classes[j] == bidi.ET; j++ {
+				if raised(j + 1) {
+					levels[i] = 2
+					break
+				}
+			}
+			if raised(i - 1) {
+				levels[i] = 2
+			}
+		case bidi.NSM:
+			if raised(i - 1) {
+				levels[i] = 2
+			}
+		}
+	}
+}
+
+// mirrored maps brackets to their mirror image, which right-to-left text
+// shows in their place (rule L4).
+var mirrored = map[rune]rune{
+	'(': ')', ')': '(',
+	'[': ']', ']': '[',
+	'{': '}', '}': '{',
+	'<': '>', '>': '<',
+	'«': '»', '»': '«',
+	'‹': '›', '›': '‹',
+}
+
+// reorderLevels applies rule L2: from the highest level down to the lowest
+// odd one, every run at that level or above is reversed. Combining marks
+// stay after the letter they belong to, as the glyphs are drawn one by one,
+// and brackets at odd levels are mirrored.
+func reorderLevels(runes []rune, levels []int) string {
+	type cluster struct {
+		runes []rune
+		level int
+	}
+	var clusters []cluster
+	highest, lowestOdd := 0, math.MaxInt
+	for i, r := range runes {
+		if len(clusters) > 0 && unicode.Is(unicode.Mn, r) {
+			last := &clusters[len(clusters)-1]
+			last.runes = append(last.runes, r)
+			continue
+		}
+		if m, ok := mirrored[r]; ok && levels[i]%2 == 1 {
+			r = m
+		}
+		clusters = append(clusters, cluster{[]rune{r}, levels[i]})
+		highest = max(highest, levels[i])
+		if levels[i]%2 == 1 {
+			lowestOdd = min(lowestOdd, levels[i])
+		}
+	}
+
+	for level := highest; level >= lowestOdd; level-- {
+		for i := 0; i < len(clusters); {
+			if clusters[i].level < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(clusters) && clusters[j].level >= level {
+				j++
+			}
+			slices.Reverse(clusters[i:j])
+			i = j
+		}
+	}
+
+	var b strings.Builder
+	for _, c := range clusters {
+		b.WriteString(string(c.runes))
+	}
+	return b.String()
+}
+
+// hasRTL reports whether s contains right-to-left characters.
+func hasRTL(s string) bool {
+	for _, r := range s {
+		switch props, _ := bidi.LookupRune(r); props.Class() {
+		case bidi.R, bidi.AL:
+			return true
+		}
+	}
+	return false
+}
+
+// baseRTL reports whether the first strongly directional character of s
+// is right to left, which makes the whole line right to left.
+func baseRTL(s string) bool {
+	for _, r := range s {
+		switch props, _ := bidi.LookupRune(r); props.Class() {
+		case bidi.L:
+			return false
+		case bidi.R, bidi.AL:
+			return true
+		}
+	}
+	return false
+}
+
+// arabicForms lists the presentation forms of Arabic letters: isolated,
+// final, initial and medial. Letters without initial and medial forms only
+// join the letter before them.
+var arabicForms = map[rune][4]rune{
+	0x0621: {0xFE80, 0, 0, 0},
+	0x0622: {0xFE81, 0xFE82, 0, 0},
+	0x0623: {0xFE83, 0xFE84, 0, 0},
+	0x0624: {0xFE85, 0xFE86, 0, 0},
+	0x0625: {0xFE87, 0xFE88, 0, 0},
+	0x0626: {0xFE89, 0xFE8A, 0xFE8B, 0xFE8C},
+	0x0627: {0xFE8D, 0xFE8E, 0, 0},
+	0x0628: {0xFE8F, 0xFE90, 0xFE91, 0xFE92},
+	0x0629: {0xFE93, 0xFE94, 0, 0},
+	0x062A: {0xFE95, 0xFE96, 0xFE97, 0xFE98},
+	0x062B: {0xFE99, 0xFE9A, 0xFE9B, 0xFE9C},
+	0x062C: {0xFE9D, 0xFE9E, 0xFE9F, 0xFEA0},
+	0x062D: {0xFEA1, 0xFEA2, 0xFEA3, 0xFEA4},
+	0x062E: {0xFEA5, 0xFEA6, 0xFEA7, 0xFEA8},
+	0x062F: {0xFEA9, 0xFEAA, 0, 0},
+	0x0630: {0xFEAB, 0xFEAC, 0, 0},
+	0x0631: {0xFEAD, 0xFEAE, 0, 0},
+	0x0632: {0xFEAF, 0xFEB0, 0, 0},
+	0x0633: {0xFEB1, 0xFEB2, 0xFEB3, 0xFEB4},
+	0x0634: {0xFEB5, 0xFEB6, 0xFEB7, 0xFEB8},
+	0x0635: {0xFEB9, 0xFEBA, 0xFEBB, 0xFEBC},
+	0x0636: {0xFEBD, 0xFEBE, 0xFEBF, 0xFEC0},
+	0x0637: {0xFEC1, 0xFEC2, 0xFEC3, 0xFEC4},
+	0x0638: {0xFEC5, 0xFEC6, 0xFEC7, 0xFEC8},
+	0x0639: {0xFEC9, 0xFECA, 0xFECB, 0xFECC},
+	0x063A: {0xFECD, 0xFECE, 0xFECF, 0xFED0},
+	0x0640: {0x0640, 0x0640, 0x0640, 0x0640}, // tatweel
+	0x0641: {0xFED1, 0xFED2, 0xFED3, 0xFED4},
+	0x0642: {0xFED5, 0xFED6, 0xFED7, 0xFED8},
+	0x0643: {0xFED9, 0xFEDA, 0xFEDB, 0xFEDC},
+	0x0644: {0xFEDD, 0xFEDE, 0xFEDF, 0xFEE0},
+	0x0645: {0xFEE1, 0xFEE2, 0xFEE3, 0xFEE4},
+	0x0646: {0xFEE5, 0xFEE6, 0xFEE7, 0xFEE8},
+	0x0647: {0xFEE9, 0xFEEA, 0xFEEB, 0xFEEC},
+	0x0648: {0xFEED, 0xFEEE, 0, 0},
+	0x0649: {0xFEEF, 0xFEF0, 0, 0},
+	0x064A: {0xFEF1, 0xFEF2, 0xFEF3, 0xFEF4},
+	// Persian and Urdu letters.
+	0x067E: {0xFB56, 0xFB57, 0xFB58, 0xFB59},
+	0x0686: {0xFB7A, 0xFB7B, 0xFB7C, 0xFB7D},
+	0x0698: {0xFB8A, 0xFB8B, 0, 0},
+	0x06A9: {0xFB8E, 0xFB8F, 0xFB90, 0xFB91},
+	0x06AF: {0xFB92, 0xFB93, 0xFB94, 0xFB95},
+	0x06CC: {0xFBFC, 0xFBFD, 0xFBFE, 0xFBFF},
+}
+
+// lamAlefForms lists the isolated and final forms of the lam-alef
+// ligatures by the alef that follows the lam.
+var lamAlefForms = map[rune][2]rune{
+	0x0622: {0xFEF5, 0xFEF6},
+	0x0623: {0xFEF7, 0xFEF8},
+	0x0625: {0xFEF9, 0xFEFA},
+	0x0627: {0xFEFB, 0xFEFC},
+}
+
+// shapeArabic replaces Arabic letters, given in logical order, with the
+// presentation forms matching how they join their neighbors. Fonts are
+// drawn glyph by glyph without a shaping engine, so this is what makes
+// Arabic script connect.
+func shapeArabic(s string) string {
+	if !strings.ContainsFunc(s, func(r rune) bool { return r >= 0x0600 && r <= 0x06FF }) {
+		return s
+	}
+
+	runes := []rune(s)
+	// neighbor returns the closest letter in the given direction, skipping
+	// the vowel marks, which do not affect joining.
+	neighbor := func(i, step int) rune {
+		for i += step; i >= 0 && i < len(runes); i += step {
+			if !isArabicMark(runes[i]) {
+				return runes[i]
+			}
+		}
+		return 0
+	}
+	joinsNext := func(r rune) bool {
+		forms, ok := arabicForms[r]
+		return ok && forms[2] != 0
+	}
+
+	out := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		forms, ok := arabicForms[r]
+		if !ok {
+			out = append(out, r)
+			continue
+		}
+		prev := neighbor(i, -1)
+		afterPrev := joinsNext(prev)
+
+		// Lam followed by alef is written as a single ligature.
+		if r == 0x0644 {
+			if j := nextLetter(runes, i); j >= 0 {
+				if lig, ok := lamAlefForms[runes[j]]; ok {
+					if afterPrev {
+						out = append(out, lig[1])
+					} else {
+						out = append(out, lig[0])
+					}
+					out = append(out, runes[i+1:j]...)
+					i = j
+					continue
+				}
+			}
+		}
+
+		// Hamza has no final form, so nothing joins it.
+		next, nextJoins := arabicForms[neighbor(i, 1)]
+		beforeNext := forms[2] != 0 && nextJoins && next[1] != 0
+		switch {
+		case afterPrev && beforeNext:
+			out = append(out, forms[3])
+		case afterPrev && forms[1] != 0:
+			out = append(out, forms[1])
+		case beforeNext:
+			out = append(out, forms[2])
+		default:
+			out = append(out, forms[0])
+		}
+	}
+	return string(out)
+}
+
+// nextLetter returns the index of the next rune after i that is not a
+// vowel mark, or -1.
+func nextLetter(runes []rune, i int) int {
+	for j := i + 1; j < len(runes); j++ {
+		if !isArabicMark(runes[j]) {
+			return j
+		}
+	}
+	return -1
+}
+
+// isArabicMark reports whether r is an Arabic vowel or other combining
+// mark.
+func isArabicMark(r rune) bool {
+	return (r >= 0x064B && r <= 0x065F) || r == 0x0670 || (r >= 0x06D6 && r <= 0x06ED)
+}
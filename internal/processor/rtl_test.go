@@ -0,0 +1,68 @@
+package processor
+
+import "testing"
+
+func TestVisualOrder(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantRTL bool
+	}{
+		// Text without right-to-left letters is left alone.
+		{"plain.mp4", "plain.mp4", false},
+		{"2024", "2024", false},
+
+		// Hebrew.
+		{"שלום עולם", "םלוע םולש", true},
+		{"שלום…", "…םולש", true},
+		// Points stay after their letters.
+		{"שָׁלוֹם", "םוֹלשָׁ", true},
+		// Brackets are mirrored.
+		{"עברית (2024)", "(2024) תירבע", true},
+		{"מחיר 50% היום", "םויה 50% ריחמ", true},
+
+		// Arabic with digits, which keep their own order.
+		{"عام 2024", "2024 ماع", true},
+		{"السعر 1,5", "1,5 رعسلا", true},
+
+		// Mixed left-to-right and right-to-left text.
+		{"file_עברית_2024.mp4", "file_2024_תירבע.mp4", false},
+		{"Movie שלום 2", "Movie 2 םולש", false},
+		{"abc אבג def", "abc גבא def", false},
+		{"part 2 of עברית", "part 2 of תירבע", false},
+		{"שלום abc", "abc םולש", true},
+		{"שלום abc def", "abc def םולש", true},
+	}
+	for _, tt := range tests {
+		got, rtl := visualOrder(tt.in)
+		if got != tt.want || rtl != tt.wantRTL {
+			t.Errorf("visualOrder(%q) = %q, %v, want %q, %v", tt.in, got, rtl, tt.want, tt.wantRTL)
+		}
+	}
+}
+
+func TestShapeArabic(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"abc", "abc"},
+		// Initial, final and isolated forms.
+		{"باب", "ﺑﺎﺏ"},
+		// Medial forms.
+		{"محمد", "ﻣﺤﻤﺪ"},
+		// Lam-alef is a ligature, and alef does not join the next letter.
+		{"سلام", "ﺳﻼﻡ"},
+		{"لا", "ﻻ"},
+		// Vowel marks do not break joining.
+		{"بَب", "ﺑَﺐ"},
+		// Digits and spaces end a word.
+		{"ب 2", "ﺏ 2"},
+		{"عام 2024", "ﻋﺎﻡ 2024"},
+	}
+	for _, tt := range tests {
+		if got := shapeArabic(tt.in); got != tt.want {
+			t.Errorf("shapeArabic(%q) = %+q, want %+q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/xi-mad/MontageGo/internal/ffprobe"
+)
+
+// Sidecar is the machine-readable description of a generated montage.
+type Sidecar struct {
+	Output  string             `json:"output"`
+	Video   *ffprobe.VideoInfo `json:"video"`
+	Crop    string             `json:"crop,omitempty"`
+	Tiles   []SidecarTile      `json:"tiles"`
+	Palette []PaletteColor     `json:"palette,omitempty"`
+}
+
+// SidecarTile describes one tile of the grid.
+type SidecarTile struct {
+	Index     int     `json:"index"`
+	Timestamp float64 `json:"timestamp"`
+	Time      string  `json:"time"`
+	Timecode  string  `json:"timecode,omitempty"`
+	Chapter   string  `json:"chapter,omitempty"`
+	Caption   string  `json:"caption,omitempty"`
+}
+
+// writeSidecar writes the sidecar JSON to the configured path, or to stdout
+// if the path is "-".
+func (p *Processor) writeSidecar(timestamps []float64) error {
+	sidecar := Sidecar{
+		Output:  p.Config.OutputPath,
+		Video:   p.VideoInfo,
+		Tiles:   make([]SidecarTile, len(timestamps)),
+		Palette: p.palette,
+	}
+	if p.crop != nil {
+		sidecar.Crop = p.crop.String()
+	}
+	for i, ts := range timestamps {
+		sidecar.Tiles[i] = SidecarTile{
+			Index:     i,
+			Timestamp: ts,
+			Time:      formatDuration(ts),
+		}
+		if p.timecoder != nil {
+			sidecar.Tiles[i].Timecode = p.timecoder.at(ts)
+		}
+		if chapter := p.chapterAt(ts); chapter >= 0 {
+			sidecar.Tiles[i].Chapter = p.chapterTitle(chapter)
+		}
+		sidecar.Tiles[i].Caption = captionAt(p.captions, ts)
+	}
+
+	data, err := json.MarshalIndent(sidecar, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sidecar: %w", err)
+	}
+	data = append(data, '\n')
+
+	if p.Config.SidecarPath == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(p.Config.SidecarPath, data, 0o644)
+}
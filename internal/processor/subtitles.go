@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xi-mad/MontageGo/internal/ffprobe"
+)
+
+// textSubtitleCodecs are the subtitle codecs ffmpeg can convert to SRT.
+// Bitmap subtitles (PGS, VobSub, DVB) carry no text to caption with.
+var textSubtitleCodecs = map[string]bool{
+	"subrip":   true,
+	"srt":      true,
+	"ass":      true,
+	"ssa":      true,
+	"webvtt":   true,
+	"mov_text": true,
+	"text":     true,
+}
+
+// subtitleTrack resolves a --captions or --burn-subtitles value to an
+// embedded text subtitle track: "auto" picks the default one, a number
+// selects the stream with that index. isStream is false if the value names
+// a file instead.
+func (p *Processor) subtitleTrack(source string) (track ffprobe.SubtitleTrack, isStream bool, err error) {
+	var textTracks []ffprobe.SubtitleTrack
+	for _, t := range p.VideoInfo.SubtitleTracks {
+		if textSubtitleCodecs[t.Codec] {
+			textTracks = append(textTracks, t)
+		}
+	}
+
+	if source == "auto" {
+		if len(textTracks) == 0 {
+			return track, false, fmt.Errorf("no text subtitle track found")
+		}
+		for _, t := range textTracks {
+			if t.Default {
+				return t, true, nil
+			}
+		}
+		return textTracks[0], true, nil
+	}
+
+	index, convErr := strconv.Atoi(source)
+	if convErr != nil {
+		return track, false, nil
+	}
+	for _, t := range p.VideoInfo.SubtitleTracks {
+		if t.Index != index {
+			continue
+		}
+		if !textSubtitleCodecs[t.Codec] {
+			return track, false, fmt.Errorf("subtitle stream %d (%s) is not a text subtitle", index, t.Codec)
+		}
+		return t, true, nil
+	}
+	return track, false, fmt.Errorf("stream %d is not a subtitle stream", index)
+}
+
+// resolveBurnSubtitles builds the ffmpeg filter drawing the subtitles named
+// by --burn-subtitles onto the frames. It returns an empty string if no
+// subtitles are burned in.
+func (p *Processor) resolveBurnSubtitles() (string, error) {
+	source := p.Config.BurnSubtitles
+	if source == "" || source == "none" {
+		return "", nil
+	}
+	if !p.hasFilter("subtitles") {
+		return "", fmt.Errorf("burning in subtitles needs an ffmpeg built with libass (subtitles filter)")
+	}
+
+	track, isStream, err := p.subtitleTrack(source)
+	if err != nil {
+		return "", err
+	}
+	if !isStream {
+		return "subtitles=filename=" + escapeFilterValue(source), nil
+	}
+
+	// The filter counts subtitle streams only, not all streams. It reads
+	// the cues with their raw timestamps, which start at the container
+	// start time rather than at zero.
+	for i, t := range p.VideoInfo.SubtitleTracks {
+		if t.Index == track.Index {
+			p.subtitlesStart = p.VideoInfo.StartTime
+			return fmt.Sprintf("subtitles=filename=%s:si=%d", escapeFilterValue(p.VideoInfo.Path), i), nil
+		}
+	}
+	return "", fmt.Errorf("stream %d is not a subtitle stream", track.Index)
+}
+
+// escapeFilterValue escapes a filter option value so that it survives both
+// the option parser and the filtergraph parser, e.g. for file names
+// containing ':' or ','.
+func escapeFilterValue(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`).Replace(s)
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`, `[`, `\[`, `]`, `\]`, `,`, `\,`, `;`, `\;`).Replace(s)
+}
@@ -0,0 +1,149 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/xi-mad/MontageGo/internal/ffprobe"
+)
+
+// headerData is what header templates are executed against. All VideoInfo
+// fields are available directly, e.g. {{.Width}} or {{.AudioTracks}}.
+type headerData struct {
+	*ffprobe.VideoInfo
+	// Filename is the base name of the input file.
+	Filename string
+	// Line1, Line2 and Line3 are the default metadata lines, for templates
+	// that only want to tweak the built-in header.
+	Line1, Line2, Line3 string
+}
+
+// headerFuncs are the helper functions available to header templates.
+var headerFuncs = template.FuncMap{
+	"humanSize": humanSize,
+	"duration":  formatDuration,
+	"bitrate":   formatBitRateValue,
+	"fps":       formatFrameRate,
+	"upper":     strings.ToUpper,
+	"lower":     strings.ToLower,
+	"join":      strings.Join,
+	"base":      filepath.Base,
+}
+
+// loadHeaderTemplate parses the header template from --header-template or
+// --header-template-file. It returns nil if neither is set.
+func (p *Processor) loadHeaderTemplate() (*template.Template, error) {
+	text := p.Config.HeaderTemplate
+	if p.Config.HeaderTemplateFile != "" {
+		if text != "" {
+			return nil, fmt.Errorf("header template and header template file cannot be used together")
+		}
+		data, err := os.ReadFile(p.Config.HeaderTemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header template file: %w", err)
+		}
+		text = string(data)
+	}
+	if text == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("header").Funcs(headerFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse header template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// headerLines returns the header text, one entry per line. The first line
+// is the title; the others are metadata.
+func (p *Processor) headerLines() ([]string, error) {
+	data := headerData{
+		VideoInfo: p.VideoInfo,
+		Filename:  filepath.Base(p.VideoInfo.Path),
+		Line1:     p.formatMetadataLine1(),
+		Line2:     p.formatMetadataLine2(),
+		Line3:     p.formatMetadataLine3(),
+	}
+
+	if p.headerTemplate == nil {
+		lines := []string{data.Filename, data.Line1, data.Line2}
+		if data.Line3 != "" {
+			lines = append(lines, data.Line3)
+		}
+		return lines, nil
+	}
+
+	var buf bytes.Buffer
+	if err := p.headerTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute header template: %w", err)
+	}
+	text := strings.TrimRight(buf.String(), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// humanSize formats a byte count with a binary unit, e.g. "1.23 GB".
+func humanSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.2f %s", value, units[unit])
+}
+
+// formatBitRateValue formats a bitrate in bits per second given as a number
+// or as ffprobe's decimal string, e.g. "4.20 Mbps" or "128 kbps".
+func formatBitRateValue(v interface{}) string {
+	var bps float64
+	switch b := v.(type) {
+	case string:
+		parsed, err := strconv.ParseFloat(b, 64)
+		if err != nil {
+			return "N/A"
+		}
+		bps = parsed
+	case int:
+		bps = float64(b)
+	case int64:
+		bps = float64(b)
+	case float64:
+		bps = b
+	default:
+		return "N/A"
+	}
+	if bps <= 0 {
+		return "N/A"
+	}
+	if bps < 1000000 {
+		return fmt.Sprintf("%.0f kbps", bps/1000)
+	}
+	return fmt.Sprintf("%.2f Mbps", bps/1000000)
+}
+
+// formatFrameRate formats an ffprobe frame rate such as "24000/1001" as
+// "23.98", or "N/A" if it cannot be parsed.
+func formatFrameRate(rate string) string {
+	parts := strings.Split(rate, "/")
+	if len(parts) == 2 {
+		if num, err := strconv.ParseFloat(parts[0], 64); err == nil {
+			if den, err := strconv.ParseFloat(parts[1], 64); err == nil && den != 0 {
+				return fmt.Sprintf("%.2f", num/den)
+			}
+		}
+	}
+	return "N/A"
+}
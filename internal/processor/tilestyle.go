@@ -0,0 +1,165 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/fogleman/gg"
+	"github.com/xi-mad/MontageGo/internal/csscolor"
+	"golang.org/x/image/draw"
+)
+
+// tileStyle holds the resolved tile decorations for one montage.
+type tileStyle struct {
+	border      float64
+	borderColor color.Color
+	radius      float64
+	// mask is the shape of a tile with rounded corners, nil for square
+	// corners.
+	mask *image.Alpha
+	// shadow is the blurred shape of a tile, drawn shadowX, shadowY from
+	// the tile's top-left corner, or nil for no shadow.
+	shadow           *image.Alpha
+	shadowColor      color.Color
+	shadowX, shadowY int
+}
+
+// resolveTileStyle validates the tile decoration options and prepares the
+// masks for tiles of the given size. It returns nil if tiles are drawn
+// plain.
+func (p *Processor) resolveTileStyle(thumbWidth, thumbHeight int) (*tileStyle, error) {
+	cfg := p.Config
+	if cfg.TileBorder < 0 || cfg.TileRadius < 0 || cfg.TileShadowBlur < 0 {
+		return nil, fmt.Errorf("tile border, corner radius and shadow blur must not be negative")
+	}
+	if err := checkOpacity("tile shadow", cfg.TileShadowOpacity); err != nil {
+		return nil, err
+	}
+	withShadow := cfg.TileShadowOpacity > 0 && (cfg.TileShadowOffset != 0 || cfg.TileShadowBlur > 0)
+	if cfg.TileBorder == 0 && cfg.TileRadius == 0 && !withShadow {
+		return nil, nil
+	}
+
+	// Corners cannot be rounder than half the shorter side.
+	style := &tileStyle{
+		border: float64(cfg.TileBorder),
+		radius: math.Min(float64(cfg.TileRadius), float64(min(thumbWidth, thumbHeight))/2),
+	}
+	if style.border > 0 {
+		var err error
+		if style.borderColor, err = csscolor.Parse(cfg.TileBorderColor); err != nil {
+			return nil, fmt.Errorf("invalid --tile-border-color: %w", err)
+		}
+	}
+	if style.radius > 0 {
+		style.mask = tileMask(thumbWidth, thumbHeight, style.radius)
+	}
+
+	if withShadow {
+		c, err := csscolor.Parse(cfg.TileShadowColor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --tile-shadow-color: %w", err)
+		}
+		style.shadowColor = withOpacity(c, cfg.TileShadowOpacity)
+
+		// The shadow spreads up to blur pixels beyond the tile, so its mask
+		// is padded by that much on every side.
+		blur := cfg.TileShadowBlur
+		shape := tileMask(thumbWidth, thumbHeight, style.radius)
+		style.shadow = image.NewAlpha(image.Rect(0, 0, thumbWidth+2*blur, thumbHeight+2*blur))
+		draw.Draw(style.shadow, shape.Bounds().Add(image.Pt(blur, blur)), shape, image.Point{}, draw.Src)
+		if blur > 0 {
+			boxBlur(style.shadow.Pix, style.shadow.Rect.Dx(), style.shadow.Rect.Dy(), style.shadow.Stride, 1, blur)
+		}
+		style.shadowX = cfg.TileShadowOffset - blur
+		style.shadowY = cfg.TileShadowOffset - blur
+	}
+	return style, nil
+}
+
+// tileMask returns the shape of a tile, with corners rounded by radius.
+func tileMask(width, height int, radius float64) *image.Alpha {
+	mc := gg.NewContext(width, height)
+	if radius > 0 {
+		mc.DrawRoundedRectangle(0, 0, float64(width), float64(height), radius)
+	} else {
+		mc.DrawRectangle(0, 0, float64(width), float64(height))
+	}
+	mc.Fill()
+	return mc.AsMask()
+}
+
+// drawShadow draws the shadow of the tile at x, y. Shadows are drawn for
+// all tiles before any tile, so that they never fall on a neighbor.
+func (s *tileStyle) drawShadow(dc *gg.Context, x, y int) {
+	if s == nil || s.shadow == nil {
+		return
+	}
+	dst := dc.Image().(draw.Image)
+	r := s.shadow.Bounds().Add(image.Pt(x+s.shadowX, y+s.shadowY))
+	draw.DrawMask(dst, r, image.NewUniform(s.shadowColor), image.Point{}, s.shadow, image.Point{}, draw.Over)
+}
+
+// drawTile draws a tile at x, y with its corners rounded and its border on
+// top. The border lies within the tile, so it does not change the layout.
+func (s *tileStyle) drawTile(dc *gg.Context, img image.Image, x, y int) {
+	if s == nil || s.mask == nil {
+		dc.DrawImage(img, x, y)
+	} else {
+		bounds := img.Bounds()
+		dst := dc.Image().(draw.Image)
+		r := image.Rect(x, y, x+bounds.Dx(), y+bounds.Dy())
+		draw.DrawMask(dst, r, img, bounds.Min, s.mask, image.Point{}, draw.Over)
+	}
+
+	if s == nil || s.border == 0 {
+		return
+	}
+	bounds := img.Bounds()
+	inset := s.border / 2
+	dc.SetColor(s.borderColor)
+	dc.SetLineWidth(s.border)
+	dc.DrawRoundedRectangle(float64(x)+inset, float64(y)+inset, float64(bounds.Dx())-s.border, float64(bounds.Dy())-s.border, math.Max(s.radius-inset, 0))
+	dc.Stroke()
+}
+
+// boxBlur blurs 8-bit pixel data in place with three passes of a box
+// filter, which comes close to a Gaussian blur reaching radius pixels. pix
+// holds height rows of width pixels, channels bytes each, stride bytes
+// apart; every channel is blurred on its own.
+func boxBlur(pix []uint8, width, height, stride, channels, radius int) {
+	r := max(radius/3, 1)
+	buf := make([]uint8, max(width, height))
+	for pass := 0; pass < 3; pass++ {
+		for c := 0; c < channels; c++ {
+			for y := 0; y < height; y++ {
+				blurLine(pix[y*stride+c:], width, channels, r, buf)
+			}
+			for x := 0; x < width; x++ {
+				blurLine(pix[x*channels+c:], height, stride, r, buf)
+			}
+		}
+	}
+}
+
+// blurLine replaces n values, step bytes apart, with the average of the
+// values within r of each, repeating the end values beyond the ends.
+func blurLine(line []uint8, n, step, r int, buf []uint8) {
+	at := func(i int) int {
+		return int(line[min(max(i, 0), n-1)*step])
+	}
+	window := 2*r + 1
+	sum := 0
+	for i := -r; i <= r; i++ {
+		sum += at(i)
+	}
+	for i := 0; i < n; i++ {
+		buf[i] = uint8((sum + window/2) / window)
+		sum += at(i+r+1) - at(i-r)
+	}
+	for i := 0; i < n; i++ {
+		line[i*step] = buf[i]
+	}
+}
@@ -0,0 +1,95 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// timecoder converts presentation times to SMPTE timecodes, counting from
+// the video's embedded start timecode.
+type timecoder struct {
+	// rate is the real frame rate, e.g. 29.97.
+	rate float64
+	// nominal is the timecode frame rate, e.g. 30 for 29.97.
+	nominal int
+	// dropFrame is set for drop-frame timecode (29.97 and 59.94 only).
+	dropFrame bool
+	// start is the frame count of the start timecode.
+	start int
+}
+
+// newTimecoder builds a timecoder for the video. An unparsable start
+// timecode is an error; a missing one starts at 00:00:00:00.
+func (p *Processor) newTimecoder() (*timecoder, error) {
+	rate := p.frameRate()
+	tc := &timecoder{
+		rate:    rate,
+		nominal: int(math.Round(rate)),
+	}
+	if tc.nominal <= 0 {
+		tc.nominal = 25
+	}
+
+	start := p.VideoInfo.StartTimecode
+	if start == "" {
+		return tc, nil
+	}
+
+	// Drop-frame timecodes separate the frames with ';' (or '.').
+	tc.dropFrame = strings.ContainsAny(start, ";.") && (tc.nominal == 30 || tc.nominal == 60) && rate != float64(tc.nominal)
+
+	var hh, mm, ss, ff int
+	normalized := strings.NewReplacer(";", ":", ".", ":").Replace(start)
+	if _, err := fmt.Sscanf(normalized, "%d:%d:%d:%d", &hh, &mm, &ss, &ff); err != nil {
+		return nil, fmt.Errorf("invalid start timecode %q", start)
+	}
+	if mm >= 60 || ss >= 60 || ff >= tc.nominal {
+		return nil, fmt.Errorf("invalid start timecode %q", start)
+	}
+
+	frames := ((hh*60+mm)*60+ss)*tc.nominal + ff
+	if tc.dropFrame {
+		totalMinutes := hh*60 + mm
+		frames -= tc.dropCount() * (totalMinutes - totalMinutes/10)
+	}
+	tc.start = frames
+
+	return tc, nil
+}
+
+// dropCount is the number of frame numbers skipped each minute, except
+// every tenth minute, in drop-frame timecode.
+func (tc *timecoder) dropCount() int {
+	return tc.nominal / 15
+}
+
+// at returns the timecode of the frame shown at the given number of seconds
+// after the start of the video.
+func (tc *timecoder) at(seconds float64) string {
+	return tc.format(tc.start + int(math.Round(seconds*tc.rate)))
+}
+
+// format formats a frame count as HH:MM:SS:FF, or HH:MM:SS;FF for
+// drop-frame timecode. Hours wrap around at 24.
+func (tc *timecoder) format(frame int) string {
+	sep := ":"
+	if tc.dropFrame {
+		sep = ";"
+		drop := tc.dropCount()
+		framesPerMinute := tc.nominal*60 - drop
+		framesPer10Minutes := tc.nominal*600 - drop*9
+
+		tens := frame / framesPer10Minutes
+		rest := frame % framesPer10Minutes
+		frame += drop * 9 * tens
+		if rest > drop {
+			frame += drop * ((rest - drop) / framesPerMinute)
+		}
+	}
+
+	ff := frame % tc.nominal
+	totalSeconds := frame / tc.nominal
+	hh := totalSeconds / 3600 % 24
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", hh, totalSeconds/60%60, totalSeconds%60, sep, ff)
+}
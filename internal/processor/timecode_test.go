@@ -0,0 +1,98 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/xi-mad/MontageGo/internal/ffprobe"
+	"github.com/xi-mad/MontageGo/pkg/config"
+)
+
+func newTestTimecoder(t *testing.T, frameRate, start string) *timecoder {
+	t.Helper()
+	p := New(&config.Config{}, &ffprobe.VideoInfo{AvgFrameRate: frameRate, StartTimecode: start})
+	tc, err := p.newTimecoder()
+	if err != nil {
+		t.Fatalf("newTimecoder(%s, %q): %v", frameRate, start, err)
+	}
+	return tc
+}
+
+func TestTimecoderFormat(t *testing.T) {
+	tests := []struct {
+		frameRate string
+		start     string
+		frame     int
+		want      string
+	}{
+		// Non-drop-frame rates count every frame.
+		{"25/1", "", 0, "00:00:00:00"},
+		{"25/1", "", 24, "00:00:00:24"},
+		{"25/1", "", 25, "00:00:01:00"},
+		{"25/1", "", 90000, "01:00:00:00"},
+		{"24000/1001", "00:00:00:00", 1440, "00:01:00:00"},
+		{"30000/1001", "00:00:00:00", 1800, "00:01:00:00"},
+
+		// 29.97 drop-frame skips frame numbers 00 and 01 at the start of
+		// every minute except each tenth one.
+		{"30000/1001", "00:00:00;00", 1799, "00:00:59;29"},
+		{"30000/1001", "00:00:00;00", 1800, "00:01:00;02"},
+		{"30000/1001", "00:00:00;00", 1801, "00:01:00;03"},
+		{"30000/1001", "00:00:00;00", 3597, "00:01:59;29"},
+		{"30000/1001", "00:00:00;00", 3598, "00:02:00;02"},
+		{"30000/1001", "00:00:00;00", 17981, "00:09:59;29"},
+		{"30000/1001", "00:00:00;00", 17982, "00:10:00;00"},
+		{"30000/1001", "00:00:00;00", 17983, "00:10:00;01"},
+		{"30000/1001", "00:00:00;00", 107892, "01:00:00;00"},
+
+		// 59.94 drop-frame skips four frame numbers.
+		{"60000/1001", "00:00:00;00", 3599, "00:00:59;59"},
+		{"60000/1001", "00:00:00;00", 3600, "00:01:00;04"},
+		{"60000/1001", "00:00:00;00", 35964, "00:10:00;00"},
+
+		// Hours wrap around at 24.
+		{"25/1", "", 25 * 3600 * 25, "01:00:00:00"},
+	}
+	for _, tt := range tests {
+		tc := newTestTimecoder(t, tt.frameRate, tt.start)
+		if got := tc.format(tt.frame); got != tt.want {
+			t.Errorf("format(%d) at %s %q = %s, want %s", tt.frame, tt.frameRate, tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestTimecoderStart(t *testing.T) {
+	tests := []struct {
+		frameRate string
+		start     string
+		seconds   float64
+		want      string
+	}{
+		{"25/1", "10:00:00:00", 0, "10:00:00:00"},
+		{"25/1", "10:00:00:00", 61.2, "10:01:01:05"},
+		{"24000/1001", "01:00:00:00", 0, "01:00:00:00"},
+		// The start timecode is itself in drop-frame numbering.
+		{"30000/1001", "01:00:00;00", 0, "01:00:00;00"},
+		{"30000/1001", "00:00:59;29", 1001.0 / 30000, "00:01:00;02"},
+		{"30000/1001", "00:09:59;29", 1001.0 / 30000, "00:10:00;00"},
+		{"30000/1001", "00:00:00;00", 1800 * 1001.0 / 30000, "00:01:00;02"},
+		// A period also marks drop-frame timecode.
+		{"30000/1001", "00:00:00.00", 1800 * 1001.0 / 30000, "00:01:00;02"},
+		// Integer rates never drop frames, whatever the separator.
+		{"30/1", "00:00:00;00", 60, "00:01:00:00"},
+	}
+	for _, tt := range tests {
+		tc := newTestTimecoder(t, tt.frameRate, tt.start)
+		if got := tc.at(tt.seconds); got != tt.want {
+			t.Errorf("at(%v) at %s from %q = %s, want %s", tt.seconds, tt.frameRate, tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestTimecoderInvalidStart(t *testing.T) {
+	for _, start := range []string{"garbage", "00:60:00:00", "00:00:60:00", "00:00:00:25"} {
+		p := New(&config.Config{}, &ffprobe.VideoInfo{AvgFrameRate: "25/1", StartTimecode: start})
+		if _, err := p.newTimecoder(); err == nil {
+			t.Errorf("newTimecoder(%q) succeeded, want an error", start)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package processor
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fogleman/gg"
+	"github.com/xi-mad/MontageGo/internal/csscolor"
+)
+
+const (
+	// timestampInset is the distance between a timestamp and the tile edges.
+	timestampInset = 8
+	// timestampBoxPadding is the space between the text and its background box.
+	timestampBoxPadding = 4
+	// textLineFactor is the height of a line of text relative to the font
+	// size, covering ascenders and descenders.
+	textLineFactor = 1.2
+)
+
+// timestampStyle holds the resolved timestamp settings for one montage.
+type timestampStyle struct {
+	position  string
+	fontSize  float64
+	color     color.Color
+	shadow    color.Color
+	box       color.Color // nil if no background box is drawn
+	frameRate float64
+}
+
+// resolveTimestampStyle validates the timestamp options and parses colors.
+// It returns nil if timestamps are disabled.
+func (p *Processor) resolveTimestampStyle() (*timestampStyle, error) {
+	if !p.Config.ShowTimestamp {
+		return nil, nil
+	}
+
+	style := &timestampStyle{
+		position:  p.Config.TimestampPosition,
+		fontSize:  float64(p.Config.TimestampSize),
+		frameRate: p.frameRate(),
+	}
+	switch style.position {
+	case "":
+		style.position = "bottom-left"
+	case "top-left", "top-right", "bottom-left", "bottom-right", "center", "below":
+	default:
+		return nil, fmt.Errorf("unsupported timestamp position: %s", style.position)
+	}
+	switch p.Config.TimestampFormat {
+	case "", "hms", "hms-ms", "frame", "timecode":
+	default:
+		return nil, fmt.Errorf("unsupported timestamp format: %s", p.Config.TimestampFormat)
+	}
+	if style.fontSize <= 0 {
+		style.fontSize = 18
+	}
+
+	// Timestamps follow the main font color unless told otherwise.
+	colorName, colorFlag := p.Config.TimestampColor, "timestamp-color"
+	if colorName == "" {
+		colorName, colorFlag = p.Config.FontColor, "font-color"
+	}
+	var err error
+	if style.color, err = csscolor.Parse(colorName); err != nil {
+		return nil, fmt.Errorf("invalid --%s: %w", colorFlag, err)
+	}
+	if style.shadow, err = csscolor.Parse(p.Config.ShadowColor); err != nil {
+		return nil, fmt.Errorf("invalid --shadow-color: %w", err)
+	}
+
+	if p.Config.TimestampBgOpacity > 0 {
+		bg, err := csscolor.Parse(p.Config.TimestampBgColor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --timestamp-bg-color: %w", err)
+		}
+		style.box = withOpacity(bg, p.Config.TimestampBgOpacity)
+	}
+
+	return style, nil
+}
+
+// belowHeight returns the height of the label row reserved under each tile.
+func (s *timestampStyle) belowHeight() int {
+	if s == nil || s.position != "below" {
+		return 0
+	}
+	return int(math.Ceil(s.fontSize*textLineFactor)) + 2*timestampBoxPadding
+}
+
+// bottomClearance returns the height a timestamp in a bottom corner of the
+// tile takes up, so that other overlays can be placed above it.
+func (s *timestampStyle) bottomClearance() int {
+	if s == nil || (s.position != "bottom-left" && s.position != "bottom-right") {
+		return 0
+	}
+	return int(math.Ceil(s.fontSize*textLineFactor)) + 2*timestampBoxPadding + timestampInset
+}
+
+// formatTimestamp renders a tile time according to --timestamp-format.
+// Timecodes count from the embedded start timecode.
+func (p *Processor) formatTimestamp(seconds, frameRate float64) string {
+	switch p.Config.TimestampFormat {
+	case "hms-ms":
+		d := time.Duration(math.Round(seconds*1000)) * time.Millisecond
+		return fmt.Sprintf("%s.%03d", formatDuration(d.Truncate(time.Second).Seconds()), (d%time.Second)/time.Millisecond)
+	case "frame":
+		return "#" + strconv.Itoa(int(math.Round(seconds*frameRate)))
+	case "timecode":
+		if p.timecoder != nil {
+			return p.timecoder.at(seconds)
+		}
+		return formatDuration(seconds)
+	default:
+		return formatDuration(seconds)
+	}
+}
+
+// draw draws a timestamp on (or below) the tile at x, y. The font must
+// already be loaded at the style's size.
+func (s *timestampStyle) draw(dc *gg.Context, text string, x, y, thumbWidth, thumbHeight int) {
+	w, h := dc.MeasureString(text)
+	boxW := w + 2*timestampBoxPadding
+	boxH := h + 2*timestampBoxPadding
+
+	// Top-left corner of the background box.
+	var bx, by float64
+	left := float64(x + timestampInset)
+	right := float64(x+thumbWidth-timestampInset) - boxW
+	top := float64(y + timestampInset)
+	bottom := float64(y+thumbHeight-timestampInset) - boxH
+	switch s.position {
+	case "top-left":
+		bx, by = left, top
+	case "top-right":
+		bx, by = right, top
+	case "bottom-right":
+		bx, by = right, bottom
+	case "center":
+		bx = float64(x) + (float64(thumbWidth)-boxW)/2
+		by = float64(y) + (float64(thumbHeight)-boxH)/2
+	case "below":
+		bx = float64(x) + (float64(thumbWidth)-boxW)/2
+		by = float64(y + thumbHeight)
+	default: // bottom-left
+		bx, by = left, bottom
+	}
+
+	if s.box != nil {
+		dc.SetColor(s.box)
+		dc.DrawRectangle(bx, by, boxW, boxH)
+		dc.Fill()
+	}
+
+	textX := bx + timestampBoxPadding
+	textY := by + timestampBoxPadding
+	dc.SetColor(s.shadow)
+	dc.DrawStringAnchored(text, textX+1, textY+1, 0, 1)
+	dc.SetColor(s.color)
+	dc.DrawStringAnchored(text, textX, textY, 0, 1)
+}
+
+// frameRate returns the video's average frame rate, or 25 if unknown.
+func (p *Processor) frameRate() float64 {
+	parts := strings.Split(p.VideoInfo.AvgFrameRate, "/")
+	if len(parts) == 2 {
+		if num, err := strconv.ParseFloat(parts[0], 64); err == nil {
+			if den, err := strconv.ParseFloat(parts[1], 64); err == nil && den != 0 && num != 0 {
+				return num / den
+			}
+		}
+	}
+	return 25.0
+}
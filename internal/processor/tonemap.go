@@ -0,0 +1,203 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// toneMapFilter returns the ffmpeg filter chain used to bring HDR frames
+// into SDR BT.709: linearize, convert primaries, apply the Hable curve and
+// re-encode with the BT.709 transfer. The input transfer, primaries and
+// matrix are given explicitly, as zscale cannot convert frames whose tags
+// are missing.
+func toneMapFilter(transfer, primaries, matrix string) string {
+	return fmt.Sprintf("zscale=tin=%s:pin=%s:min=%s:t=linear:npl=100,format=gbrpf32le,zscale=p=bt709,tonemap=tonemap=hable:desat=0,zscale=t=bt709:m=bt709:r=tv,format=yuv420p",
+		transfer, primaries, matrix)
+}
+
+// hdrTransfer returns the transfer function of the HDR signal: the stream's
+// own if it is PQ or HLG, or PQ when tone mapping is forced on a source that
+// does not say. ok is false for a Dolby Vision stream without an HDR10 or
+// HLG base layer, which only a Dolby Vision aware renderer can decode.
+func (p *Processor) hdrTransfer() (transfer string, ok bool) {
+	switch p.VideoInfo.ColorTransfer {
+	case "smpte2084", "arib-std-b67":
+		return p.VideoInfo.ColorTransfer, true
+	}
+	if p.VideoInfo.HDRFormat == "Dolby Vision" {
+		return "", false
+	}
+	return "smpte2084", true
+}
+
+// resolveToneMapping decides whether frames need tone mapping according to
+// --tonemap and the detected HDR format, and how it will be done.
+func (p *Processor) resolveToneMapping() error {
+	switch p.Config.ToneMap {
+	case "", "auto":
+		if p.VideoInfo.HDRFormat == "" {
+			return nil
+		}
+	case "on":
+	case "off":
+		return nil
+	default:
+		return fmt.Errorf("unsupported tonemap mode: %s", p.Config.ToneMap)
+	}
+
+	transfer, ok := p.hdrTransfer()
+	if !ok {
+		if p.Config.ToneMap == "on" {
+			return fmt.Errorf("cannot tone map Dolby Vision without an HDR10 or HLG base layer")
+		}
+		p.warnf("Dolby Vision without an HDR10 or HLG base layer cannot be tone mapped; thumbnails keep their HDR colors")
+		return nil
+	}
+
+	// BT.2020 is assumed where the stream does not tag its colors.
+	primaries := p.VideoInfo.ColorPrimaries
+	if primaries == "" || primaries == "unknown" {
+		primaries = "bt2020"
+	}
+	matrix := p.VideoInfo.ColorSpace
+	if matrix == "" || matrix == "unknown" {
+		matrix = "bt2020nc"
+	}
+
+	// zscale is an optional ffmpeg dependency; without it we tone map the
+	// decoded frames ourselves.
+	if p.hasFilter("zscale") && p.hasFilter("tonemap") {
+		p.toneMapChain = toneMapFilter(transfer, primaries, matrix)
+	} else {
+		p.toneMapper = newToneMapper(transfer)
+	}
+	return nil
+}
+
+// toneMapper is the CPU fallback for HDR to SDR conversion. It works on
+// 8-bit RGB frames that were decoded without any color management, so the
+// result is an approximation of the ffmpeg filter chain.
+type toneMapper struct {
+	// linear maps an 8-bit code value to linear light, 1.0 being 100 nits.
+	linear [256]float64
+	// encode maps linear light in [0, 1] to an 8-bit BT.709 code value.
+	encode [4096]uint8
+	// white normalizes the Hable curve so that the peak maps to 1.0.
+	white float64
+}
+
+// newToneMapper builds the lookup tables for the given transfer function.
+// Anything that is not HLG is treated as PQ (SMPTE ST 2084).
+func newToneMapper(transfer string) *toneMapper {
+	t := &toneMapper{}
+
+	toLinear := pqToLinear
+	peak := 100.0 // 10000 nits, the PQ maximum
+	if transfer == "arib-std-b67" {
+		toLinear = hlgToLinear
+		peak = 10 // 1000 nits nominal HLG display
+	}
+	for i := range t.linear {
+		t.linear[i] = toLinear(float64(i) / 255)
+	}
+	t.white = hable(peak)
+
+	for i := range t.encode {
+		v := float64(i) / float64(len(t.encode)-1)
+		// BT.709 / sRGB-like display gamma.
+		var e float64
+		if v <= 0.0031308 {
+			e = 12.92 * v
+		} else {
+			e = 1.055*math.Pow(v, 1/2.4) - 0.055
+		}
+		t.encode[i] = uint8(math.Round(e * 255))
+	}
+
+	return t
+}
+
+// mapRGB tone maps one pixel.
+func (t *toneMapper) mapRGB(r, g, b uint8) (uint8, uint8, uint8) {
+	lr, lg, lb := t.linear[r], t.linear[g], t.linear[b]
+
+	// BT.2020 to BT.709 primaries in linear light.
+	r709 := 1.6605*lr - 0.5876*lg - 0.0728*lb
+	g709 := -0.1246*lr + 1.1329*lg - 0.0083*lb
+	b709 := -0.0182*lr - 0.1006*lg + 1.1187*lb
+
+	return t.encodeValue(r709), t.encodeValue(g709), t.encodeValue(b709)
+}
+
+// encodeValue applies the Hable curve and the output transfer function.
+func (t *toneMapper) encodeValue(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	v = hable(v) / t.white
+	if v >= 1 {
+		return 255
+	}
+	return t.encode[int(v*float64(len(t.encode)-1))]
+}
+
+// apply returns a tone mapped copy of img.
+func (t *toneMapper) apply(img image.Image) image.Image {
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			r, g, bl := t.mapRGB(c.R, c.G, c.B)
+			out.SetRGBA(x, y, color.RGBA{R: r, G: g, B: bl, A: 255})
+		}
+	}
+	return out
+}
+
+// pqToLinear is the SMPTE ST 2084 EOTF, scaled so that 1.0 is 100 nits.
+func pqToLinear(v float64) float64 {
+	const (
+		m1 = 0.1593017578125
+		m2 = 78.84375
+		c1 = 0.8359375
+		c2 = 18.8515625
+		c3 = 18.6875
+	)
+	p := math.Pow(v, 1/m2)
+	num := math.Max(p-c1, 0)
+	den := c2 - c3*p
+	return math.Pow(num/den, 1/m1) * 100
+}
+
+// hlgToLinear is the ARIB STD-B67 inverse OETF followed by the reference
+// OOTF for a 1000 nit display, scaled so that 1.0 is 100 nits.
+func hlgToLinear(v float64) float64 {
+	const (
+		a = 0.17883277
+		b = 0.28466892
+		c = 0.55991073
+	)
+	var e float64
+	if v <= 0.5 {
+		e = v * v / 3
+	} else {
+		e = (math.Exp((v-c)/a) + b) / 12
+	}
+	return math.Pow(e, 1.2) * 10
+}
+
+// hable is John Hable's filmic curve, as used by ffmpeg's tonemap filter.
+func hable(x float64) float64 {
+	const (
+		a = 0.15
+		b = 0.50
+		c = 0.10
+		d = 0.20
+		e = 0.02
+		f = 0.30
+	)
+	return (x*(a*x+c*b)+d*e)/(x*(a*x+b)+d*f) - e/f
+}
@@ -2,32 +2,96 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds all the configuration for the MontageGo tool.
 type Config struct {
-	InputPath       string `yaml:"input_path"`
-	OutputPath      string `yaml:"output_path"`
-	Columns         int    `yaml:"columns"`
-	Rows            int    `yaml:"rows"`
-	ThumbWidth      int    `yaml:"thumb_width"`
-	ThumbHeight     int    `yaml:"thumb_height"`
-	Padding         int    `yaml:"padding"`
-	Margin          int    `yaml:"margin"`
-	HeaderHeight    int    `yaml:"header_height"`
-	FontFile        string `yaml:"font_file"`
-	FontColor       string `yaml:"font_color"`
-	ShadowColor     string `yaml:"shadow_color"`
-	BackgroundColor string `yaml:"background_color"`
-	JpegQuality     int    `yaml:"jpeg_quality"`
-	FfmpegPath      string `yaml:"ffmpeg_path"`
-	FfprobePath     string `yaml:"ffprobe_path"`
-	Quiet           bool   `yaml:"quiet"`
-	Verbose         bool   `yaml:"verbose"`
-	ShowAppLog      bool   `yaml:"show_app_log"`
-	ShowFfmpegLog   bool   `yaml:"show_ffmpeg_log"`
+	InputPath               string        `yaml:"input_path"`
+	OutputPath              string        `yaml:"output_path"`
+	OutputFormat            string        `yaml:"output_format"`
+	Preset                  string        `yaml:"preset"`
+	Columns                 int           `yaml:"columns"`
+	Rows                    int           `yaml:"rows"`
+	ThumbWidth              int           `yaml:"thumb_width"`
+	ThumbHeight             int           `yaml:"thumb_height"`
+	Padding                 int           `yaml:"padding"`
+	Margin                  int           `yaml:"margin"`
+	CanvasWidth             int           `yaml:"canvas_width"`
+	Interval                time.Duration `yaml:"interval"`
+	TileCount               int           `yaml:"count"`
+	HeaderHeight            int           `yaml:"header_height"`
+	HeaderStyle             string        `yaml:"header_style"`
+	HeaderPosition          string        `yaml:"header_position"`
+	HeaderTemplate          string        `yaml:"header_template"`
+	HeaderTemplateFile      string        `yaml:"header_template_file"`
+	Font                    string        `yaml:"font"`
+	FontFile                string        `yaml:"font_file"`
+	FontFallbacks           []string      `yaml:"font_fallbacks"`
+	FontColor               string        `yaml:"font_color"`
+	ShadowColor             string        `yaml:"shadow_color"`
+	BackgroundColor         string        `yaml:"background_color"`
+	BackgroundGradient      string        `yaml:"background_gradient"`
+	BackgroundGradientTo    string        `yaml:"background_gradient_to"`
+	BackgroundGradientAngle float64       `yaml:"background_gradient_angle"`
+	BackgroundImage         string        `yaml:"background_image"`
+	BackgroundImageFit      string        `yaml:"background_image_fit"`
+	BackgroundFrame         bool          `yaml:"background_frame"`
+	BackgroundBlur          int           `yaml:"background_blur"`
+	BackgroundDim           float64       `yaml:"background_dim"`
+	ShowTimestamp           bool          `yaml:"show_timestamp"`
+	TimestampPosition       string        `yaml:"timestamp_position"`
+	TimestampFormat         string        `yaml:"timestamp_format"`
+	TimestampSize           int           `yaml:"timestamp_size"`
+	TimestampColor          string        `yaml:"timestamp_color"`
+	TimestampBgColor        string        `yaml:"timestamp_bg_color"`
+	TimestampBgOpacity      float64       `yaml:"timestamp_bg_opacity"`
+	JpegQuality             int           `yaml:"jpeg_quality"`
+	AudioVisual             string        `yaml:"audio_visual"`
+	Barcode                 string        `yaml:"barcode"`
+	BarcodeHeight           int           `yaml:"barcode_height"`
+	BarcodeFrames           int           `yaml:"barcode_frames"`
+	BarcodeMode             string        `yaml:"barcode_mode"`
+	BarcodeOutput           string        `yaml:"barcode_output"`
+	Palette                 int           `yaml:"palette"`
+	PaletteHeader           bool          `yaml:"palette_header"`
+	TileBorder              int           `yaml:"tile_border"`
+	TileBorderColor         string        `yaml:"tile_border_color"`
+	TileRadius              int           `yaml:"tile_radius"`
+	TileShadowOffset        int           `yaml:"tile_shadow_offset"`
+	TileShadowBlur          int           `yaml:"tile_shadow_blur"`
+	TileShadowColor         string        `yaml:"tile_shadow_color"`
+	TileShadowOpacity       float64       `yaml:"tile_shadow_opacity"`
+	Logo                    string        `yaml:"logo"`
+	LogoPosition            string        `yaml:"logo_position"`
+	LogoScale               float64       `yaml:"logo_scale"`
+	LogoOpacity             float64       `yaml:"logo_opacity"`
+	Watermark               string        `yaml:"watermark"`
+	WatermarkTarget         string        `yaml:"watermark_target"`
+	WatermarkPosition       string        `yaml:"watermark_position"`
+	WatermarkScale          float64       `yaml:"watermark_scale"`
+	WatermarkOpacity        float64       `yaml:"watermark_opacity"`
+	SidecarPath             string        `yaml:"sidecar_path"`
+	Crop                    string        `yaml:"crop"`
+	ToneMap                 string        `yaml:"tonemap"`
+	Deinterlace             string        `yaml:"deinterlace"`
+	VideoStream             int           `yaml:"video_stream"`
+	Select                  string        `yaml:"select"`
+	FramesPerChapter        int           `yaml:"frames_per_chapter"`
+	ChapterLabels           bool          `yaml:"chapter_labels"`
+	Captions                string        `yaml:"captions"`
+	CaptionPosition         string        `yaml:"caption_position"`
+	CaptionSize             int           `yaml:"caption_size"`
+	CaptionLines            int           `yaml:"caption_lines"`
+	BurnSubtitles           string        `yaml:"burn_subtitles"`
+	FfmpegPath              string        `yaml:"ffmpeg_path"`
+	FfprobePath             string        `yaml:"ffprobe_path"`
+	Quiet                   bool          `yaml:"quiet"`
+	Verbose                 bool          `yaml:"verbose"`
+	ShowAppLog              bool          `yaml:"show_app_log"`
+	ShowFfmpegLog           bool          `yaml:"show_ffmpeg_log"`
 }
 
 func NewConfig() *Config {
@@ -36,13 +100,24 @@ func NewConfig() *Config {
 
 // Load reads a YAML config file from the given path and returns a Config.
 func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
 	var c Config
-	if err := yaml.Unmarshal(data, &c); err != nil {
+	if err := c.ApplyFile(path); err != nil {
 		return nil, err
 	}
 	return &c, nil
 }
+
+// ApplyFile overlays the settings of a YAML config file on c. Settings the
+// file does not mention keep their current values.
+func (c *Config) ApplyFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return c.Apply(data)
+}
+
+// Apply overlays YAML settings on c, as ApplyFile does.
+func (c *Config) Apply(data []byte) error {
+	return yaml.Unmarshal(data, c)
+}
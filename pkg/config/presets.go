@@ -0,0 +1,119 @@
+package config
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// builtinPresets holds the presets bundled in the binary.
+//
+//go:embed presets/*.yaml
+var builtinPresets embed.FS
+
+// PresetInfo describes a preset available to --preset.
+type PresetInfo struct {
+	Name string
+	// Description is the first comment line of the preset file.
+	Description string
+	// Source is the file the preset is read from, or "built-in".
+	Source string
+}
+
+// PresetDir returns the directory user presets are read from: a
+// montagego/presets folder in the user's configuration directory (e.g.
+// ~/.config/montagego/presets on Linux).
+func PresetDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "montagego", "presets"), nil
+}
+
+// ReadPreset returns the YAML of a preset. name is a path to a YAML file,
+// the name of a file in PresetDir, or a built-in preset; user presets take
+// precedence over built-in ones of the same name.
+func ReadPreset(name string) ([]byte, error) {
+	if strings.ContainsAny(name, `/\`) || isYAML(name) {
+		return os.ReadFile(name)
+	}
+
+	if dir, err := PresetDir(); err == nil {
+		for _, ext := range []string{".yaml", ".yml"} {
+			data, err := os.ReadFile(filepath.Join(dir, name+ext))
+			if err == nil {
+				return data, nil
+			}
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+	}
+
+	data, err := builtinPresets.ReadFile("presets/" + name + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("unknown preset %q (see 'presets list')", name)
+	}
+	return data, nil
+}
+
+// Presets lists the built-in and user presets, sorted by name. A user
+// preset hides the built-in preset of the same name.
+func Presets() []PresetInfo {
+	byName := make(map[string]PresetInfo)
+
+	entries, _ := builtinPresets.ReadDir("presets")
+	for _, e := range entries {
+		data, err := builtinPresets.ReadFile("presets/" + e.Name())
+		if err != nil {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		byName[name] = PresetInfo{Name: name, Description: description(data), Source: "built-in"}
+	}
+
+	if dir, err := PresetDir(); err == nil {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			if e.IsDir() || !isYAML(e.Name()) {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			name := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+			byName[name] = PresetInfo{Name: name, Description: description(data), Source: path}
+		}
+	}
+
+	presets := make([]PresetInfo, 0, len(byName))
+	for _, info := range byName {
+		presets = append(presets, info)
+	}
+	sort.Slice(presets, func(a, b int) bool { return presets[a].Name < presets[b].Name })
+	return presets
+}
+
+func isYAML(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// description returns the text of the first line of data if it is a
+// comment.
+func description(data []byte) string {
+	line, _, _ := strings.Cut(string(data), "\n")
+	if text, ok := strings.CutPrefix(strings.TrimSpace(line), "#"); ok {
+		return strings.TrimSpace(text)
+	}
+	return ""
+}
@@ -77,8 +77,8 @@ echo "\n[7/13] Testing low JPEG quality..."
 "$BINARY" "$VIDEO_FILE" -o "$OUTPUT_DIR/test_07_low_quality.jpg" --font-file "$FONT_FILE" \
     --jpeg-quality 31
 
-echo "\n[8/13] Testing with no text rendering (empty font-file, no header)..."
-"$BINARY" "$VIDEO_FILE" -o "$OUTPUT_DIR/test_08_no_text.jpg" --header 0 --font-file ""
+echo "\n[8/13] Testing with no text rendering (no header, no timestamps)..."
+"$BINARY" "$VIDEO_FILE" -o "$OUTPUT_DIR/test_08_no_text.jpg" --header 0 --timestamp=false
 
 echo "\n[9/13] Testing small thumbnail size (320px width)..."
 "$BINARY" "$VIDEO_FILE" -o "$OUTPUT_DIR/test_09_small_thumbs.jpg" --font-file "$FONT_FILE" \